@@ -0,0 +1,404 @@
+package requiem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAuditPath           = "/audit"
+	defaultAuditActorAttribute = "actor"
+	defaultAuditQueryLimit     = 100
+	maxAuditQueryLimit         = 1000
+	auditRedacted              = "[REDACTED]"
+)
+
+// defaultAuditRedact lists body fields redacted when AuditConfig.Redact is empty.
+var defaultAuditRedact = []string{"password", "secret", "token", "api_key", "apikey", "authorization"}
+
+// AuditRecord describes one audited request or MCP tool call.
+type AuditRecord struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	Timestamp time.Time         `json:"timestamp" gorm:"index"`
+	Actor     string            `json:"actor" gorm:"index"`
+	Method    string            `json:"method"`
+	Route     string            `json:"route" gorm:"index"`
+	Path      string            `json:"path"`
+	Tool      string            `json:"tool,omitempty" gorm:"index"`
+	Params    map[string]string `json:"params,omitempty" gorm:"serializer:json"`
+	Body      string            `json:"body,omitempty"`
+	Status    int               `json:"status"`
+}
+
+// AuditSink receives audit records. Write is called synchronously at the end
+// of each audited request, so slow sinks should buffer internally.
+type AuditSink interface {
+	Write(rec AuditRecord) error
+}
+
+// AuditQuery filters records returned by an AuditQuerier. Zero values match
+// everything.
+type AuditQuery struct {
+	Actor  string
+	Route  string
+	Tool   string
+	Method string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// AuditQuerier is implemented by sinks that can serve the admin query endpoint.
+type AuditQuerier interface {
+	Query(q AuditQuery) ([]AuditRecord, error)
+}
+
+// LogAuditSink writes each record to the application logger as JSON.
+type LogAuditSink struct{}
+
+func (LogAuditSink) Write(rec AuditRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	Logger.Info("AUDIT %s", b)
+	return nil
+}
+
+// GormAuditSink stores records in the audit_records table and serves queries
+// from it.
+type GormAuditSink struct {
+	DB *gorm.DB
+}
+
+// NewGormAuditSink returns a sink backed by db, migrating its table first.
+func NewGormAuditSink(db *gorm.DB) *GormAuditSink {
+	if err := db.AutoMigrate(&AuditRecord{}); err != nil {
+		Logger.Error("Could not migrate audit table: %s", err)
+	}
+	return &GormAuditSink{DB: db}
+}
+
+func (s *GormAuditSink) Write(rec AuditRecord) error {
+	return s.DB.Create(&rec).Error
+}
+
+func (s *GormAuditSink) Query(q AuditQuery) ([]AuditRecord, error) {
+	tx := s.DB.Model(&AuditRecord{})
+	if q.Actor != "" {
+		tx = tx.Where("actor = ?", q.Actor)
+	}
+	if q.Route != "" {
+		tx = tx.Where("route = ?", q.Route)
+	}
+	if q.Tool != "" {
+		tx = tx.Where("tool = ?", q.Tool)
+	}
+	if q.Method != "" {
+		tx = tx.Where("method = ?", strings.ToUpper(q.Method))
+	}
+	if !q.Since.IsZero() {
+		tx = tx.Where("timestamp >= ?", q.Since)
+	}
+	if !q.Until.IsZero() {
+		tx = tx.Where("timestamp < ?", q.Until)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultAuditQueryLimit
+	}
+	var out []AuditRecord
+	err := tx.Order("timestamp DESC, id DESC").Limit(limit).Offset(q.Offset).Find(&out).Error
+	return out, err
+}
+
+// AuditConfig configures the optional audit addon. Every non-GET request and
+// every MCP tools/call is recorded to each sink.
+type AuditConfig struct {
+	// Sinks receive every record. Defaults to a LogAuditSink.
+	Sinks []AuditSink
+	// ActorAttribute names the context attribute holding the caller's identity,
+	// set by an interceptor or MCPConfig.Authenticate. Defaults to "actor".
+	// Non-string values are formatted as JSON.
+	ActorAttribute string
+	// Actor optionally derives the actor from the context, overriding ActorAttribute.
+	Actor func(ctx HTTPContext) string
+	// Redact lists request body fields (matched case-insensitively at any depth)
+	// whose values are replaced before recording. Defaults to common credential
+	// field names.
+	Redact []string
+	// Path overrides the admin query endpoint. Defaults to the API's common route
+	// prefix plus "/audit". The endpoint is only mounted when Admin is set and a
+	// sink implements AuditQuerier.
+	Path string
+	// Admin gates the query endpoint; it should reject non-admin callers.
+	Admin HTTPInterceptor
+}
+
+// UseAudit enables the audit addon. Like the other addons it is purely additive:
+// routes are unaffected unless it is called.
+func (s *Server) UseAudit(cfg AuditConfig) {
+	if !s.auditEnabled {
+		s.controllers = append(s.controllers, &auditController{cfg: cfg})
+		s.auditEnabled = true
+	}
+}
+
+type auditController struct {
+	cfg AuditConfig
+}
+
+func (c *auditController) Load(router *Router) {
+	a := newAuditor(c.cfg)
+	router.audit = a
+
+	querier := a.querier()
+	if c.cfg.Admin == nil || querier == nil {
+		return
+	}
+	// The default path sits under the routes' common prefix, which is only
+	// known once every controller has registered its routes.
+	router.loaded = append(router.loaded, func() {
+		path := c.cfg.Path
+		if path == "" {
+			router.mu.RLock()
+			path = commonRoutePrefix(router.routes) + defaultAuditPath
+			router.mu.RUnlock()
+		}
+		router.mount(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request) {
+			ctx := newHTTPContext(w, r, nil, router, nil)
+			if c.cfg.Admin(ctx) {
+				serveAuditQuery(ctx, querier)
+			}
+		})
+	})
+}
+
+type auditor struct {
+	cfg    AuditConfig
+	redact map[string]bool
+}
+
+func newAuditor(cfg AuditConfig) *auditor {
+	if len(cfg.Sinks) == 0 {
+		cfg.Sinks = []AuditSink{LogAuditSink{}}
+	}
+	if cfg.ActorAttribute == "" {
+		cfg.ActorAttribute = defaultAuditActorAttribute
+	}
+	fields := cfg.Redact
+	if len(fields) == 0 {
+		fields = defaultAuditRedact
+	}
+	redact := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		redact[strings.ToLower(f)] = true
+	}
+	return &auditor{cfg: cfg, redact: redact}
+}
+
+func (a *auditor) querier() AuditQuerier {
+	for _, s := range a.cfg.Sinks {
+		if q, ok := s.(AuditQuerier); ok {
+			return q
+		}
+	}
+	return nil
+}
+
+func (a *auditor) actorOf(ctx HTTPContext) string {
+	if a.cfg.Actor != nil {
+		return a.cfg.Actor(ctx)
+	}
+	switch v := ctx.GetAttribute(a.cfg.ActorAttribute).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// auditCall marks a request re-dispatched by the MCP addon, carrying the tool
+// name and the actor authenticated on the MCP endpoint.
+type auditCall struct {
+	tool  string
+	actor string
+}
+
+type auditCallKey struct{}
+
+func withAuditCall(r *http.Request, call auditCall) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), auditCallKey{}, call))
+}
+
+// auditRequest starts auditing a routed request and returns the function that
+// records it; callers defer the result. It wraps ctx.Response to capture the
+// status. Requests that are not audited get a no-op.
+func (r *Router) auditRequest(ctx *HTTPContext) func() {
+	a := r.audit
+	if a == nil {
+		return func() {}
+	}
+	call, isTool := ctx.Request.Context().Value(auditCallKey{}).(auditCall)
+	if ctx.Request.Method == http.MethodGet && !isTool {
+		return func() {}
+	}
+
+	sw := &statusWriter{ResponseWriter: ctx.Response, status: http.StatusOK}
+	ctx.Response = sw
+	return func() {
+		rec := AuditRecord{
+			Timestamp: time.Now().UTC(),
+			Actor:     a.actorOf(*ctx),
+			Method:    ctx.Request.Method,
+			Path:      ctx.Request.URL.Path,
+			Params:    mux.Vars(ctx.Request),
+			Body:      a.redactBody(ctx.Body),
+			Status:    sw.status,
+		}
+		if ctx.route != nil {
+			rec.Route = stripPathRegex(ctx.route.path)
+		}
+		if isTool {
+			rec.Tool = call.tool
+			if rec.Actor == "" {
+				rec.Actor = call.actor
+			}
+		}
+		a.write(rec)
+	}
+}
+
+// recordRejectedCall audits an MCP tools/call that failed before reaching a
+// route (unknown tool, invalid arguments).
+func (a *auditor) recordRejectedCall(ctx HTTPContext, tool string, status int) {
+	a.write(AuditRecord{
+		Timestamp: time.Now().UTC(),
+		Actor:     a.actorOf(ctx),
+		Method:    ctx.Request.Method,
+		Path:      ctx.Request.URL.Path,
+		Tool:      tool,
+		Status:    status,
+	})
+}
+
+func (a *auditor) write(rec AuditRecord) {
+	for _, s := range a.cfg.Sinks {
+		if err := s.Write(rec); err != nil {
+			Logger.Error("Could not write audit record: %s", err)
+		}
+	}
+}
+
+// redactBody serializes a decoded request body with sensitive fields replaced.
+func (a *auditor) redactBody(body interface{}) string {
+	if body == nil {
+		return ""
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return ""
+	}
+	var v interface{}
+	if json.Unmarshal(b, &v) != nil {
+		return ""
+	}
+	b, _ = json.Marshal(a.redactValue(v))
+	return string(b)
+}
+
+func (a *auditor) redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if a.redact[strings.ToLower(k)] {
+				t[k] = auditRedacted
+			} else {
+				t[k] = a.redactValue(val)
+			}
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = a.redactValue(t[i])
+		}
+	}
+	return v
+}
+
+func serveAuditQuery(ctx HTTPContext, querier AuditQuerier) {
+	q := AuditQuery{
+		Actor:  ctx.GetQueryParam("actor"),
+		Route:  ctx.GetQueryParam("route"),
+		Tool:   ctx.GetQueryParam("tool"),
+		Method: ctx.GetQueryParam("method"),
+	}
+	var err error
+	if s := ctx.GetQueryParam("since"); s != "" {
+		if q.Since, err = time.Parse(time.RFC3339, s); err != nil {
+			ctx.SendStatus(http.StatusBadRequest)
+			return
+		}
+	}
+	if s := ctx.GetQueryParam("until"); s != "" {
+		if q.Until, err = time.Parse(time.RFC3339, s); err != nil {
+			ctx.SendStatus(http.StatusBadRequest)
+			return
+		}
+	}
+	if s := ctx.GetQueryParam("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 0 {
+			ctx.SendStatus(http.StatusBadRequest)
+			return
+		}
+	}
+	if q.Limit > maxAuditQueryLimit {
+		q.Limit = maxAuditQueryLimit
+	}
+	if s := ctx.GetQueryParam("offset"); s != "" {
+		if q.Offset, err = strconv.Atoi(s); err != nil || q.Offset < 0 {
+			ctx.SendStatus(http.StatusBadRequest)
+			return
+		}
+	}
+
+	records, err := querier.Query(q)
+	if err != nil {
+		ctx.SendStatus(http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []AuditRecord{}
+	}
+	ctx.SendJSON(records)
+}
+
+// statusWriter is an http.ResponseWriter that remembers the status it wrote.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,141 @@
+package requiem
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type auditLogin struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type accountsController struct{}
+
+func (c accountsController) Load(router *Router) {
+	r := router.NewRestRouter("/accounts")
+	setActor := func(ctx HTTPContext) bool {
+		ctx.SetAttribute("actor", ctx.Request.Header.Get("X-User"))
+		return true
+	}
+	r.Get("/{id}", func(ctx HTTPContext) {
+		ctx.SendJSON(map[string]string{"id": ctx.GetParam("id")})
+	}, setActor)
+	r.Post("/{id}/login", func(ctx HTTPContext) {
+		ctx.SendStatus(http.StatusCreated)
+	}, auditLogin{}, setActor)
+}
+
+type memoryAuditSink struct {
+	records []AuditRecord
+}
+
+func (s *memoryAuditSink) Write(rec AuditRecord) error {
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func auditTestRouter(t *testing.T, sinks ...AuditSink) *Router {
+	t.Helper()
+	return newRouter(defaultBasePath, nil, []IHttpController{
+		accountsController{},
+		&mcpController{cfg: MCPConfig{Name: "T", Version: "1", Authenticate: func(ctx HTTPContext) bool {
+			ctx.SetAttribute("actor", "mcp-user")
+			return true
+		}}},
+		&auditController{cfg: AuditConfig{Sinks: sinks, Admin: func(ctx HTTPContext) bool {
+			if ctx.Request.Header.Get("X-User") != "admin" {
+				ctx.SendStatus(http.StatusForbidden)
+				return false
+			}
+			return true
+		}}},
+	})
+}
+
+func TestAudit_RecordsMutatingRequestsOnly(t *testing.T) {
+	sink := &memoryAuditSink{}
+	r := auditTestRouter(t, sink)
+
+	serve(r, http.MethodGet, "/api/accounts/7", "", nil)
+	assert.Empty(t, sink.records)
+
+	serve(r, http.MethodPost, "/api/accounts/7/login", `{"username":"bob","password":"hunter2"}`, map[string]string{"X-User": "bob"})
+
+	assert.Len(t, sink.records, 1)
+	rec := sink.records[0]
+	assert.Equal(t, "bob", rec.Actor)
+	assert.Equal(t, http.MethodPost, rec.Method)
+	assert.Equal(t, "/accounts/{id}/login", rec.Route)
+	assert.Equal(t, map[string]string{"id": "7"}, rec.Params)
+	assert.Equal(t, http.StatusCreated, rec.Status)
+	assert.Contains(t, rec.Body, `"username":"bob"`)
+	assert.Contains(t, rec.Body, `"password":"[REDACTED]"`)
+	assert.NotContains(t, rec.Body, "hunter2")
+}
+
+func TestAudit_RecordsInvalidBodyStatus(t *testing.T) {
+	sink := &memoryAuditSink{}
+	r := auditTestRouter(t, sink)
+
+	serve(r, http.MethodPost, "/api/accounts/7/login", `{}`, nil)
+
+	assert.Len(t, sink.records, 1)
+	assert.Equal(t, http.StatusBadRequest, sink.records[0].Status)
+}
+
+func TestAudit_RecordsMCPToolCalls(t *testing.T) {
+	sink := &memoryAuditSink{}
+	r := auditTestRouter(t, sink)
+
+	resp := rpc(t, r, "tools/call", map[string]interface{}{
+		"name":      "get_accounts_id",
+		"arguments": map[string]interface{}{"id": "9"},
+	}, nil)
+	assert.Nil(t, resp.Error)
+
+	resp = rpc(t, r, "tools/call", map[string]interface{}{"name": "no_such_tool"}, nil)
+	assert.NotNil(t, resp.Error)
+
+	assert.Len(t, sink.records, 2)
+	assert.Equal(t, "get_accounts_id", sink.records[0].Tool)
+	assert.Equal(t, "mcp-user", sink.records[0].Actor)
+	assert.Equal(t, http.MethodGet, sink.records[0].Method)
+	assert.Equal(t, http.StatusOK, sink.records[0].Status)
+	assert.Equal(t, "no_such_tool", sink.records[1].Tool)
+	assert.Equal(t, http.StatusBadRequest, sink.records[1].Status)
+}
+
+func TestAudit_GormSinkQueryEndpoint(t *testing.T) {
+	r := auditTestRouter(t, NewGormAuditSink(testDB(t, "")))
+
+	for _, user := range []string{"alice", "bob"} {
+		serve(r, http.MethodPost, "/api/accounts/1/login", `{"username":"x","password":"y"}`, map[string]string{"X-User": user})
+	}
+
+	rec := serve(r, http.MethodGet, "/api/audit?actor=alice", "", nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = serve(r, http.MethodGet, "/api/audit?actor=alice", "", map[string]string{"X-User": "admin"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var records []AuditRecord
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &records))
+	assert.Len(t, records, 1)
+	assert.Equal(t, "alice", records[0].Actor)
+	assert.Equal(t, map[string]string{"id": "1"}, records[0].Params)
+}
+
+func TestAudit_QueryPathIndependentOfControllerOrder(t *testing.T) {
+	admin := func(ctx HTTPContext) bool { return true }
+	r := newRouter(defaultBasePath, nil, []IHttpController{
+		&auditController{cfg: AuditConfig{Sinks: []AuditSink{NewGormAuditSink(testDB(t, ""))}, Admin: admin}},
+		accountsController{},
+	})
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/api/accounts/audit", "", nil).Code)
+	assert.Empty(t, r.loaded)
+}
@@ -0,0 +1,627 @@
+// Package clientgen generates a typed Go client package from an OpenAPI 3
+// document, such as the one requiem's Server.GetOpenAPISpec returns.
+//
+// The generated package has a Client with one method per operation, named
+// after its operationId. Methods take a context, the operation's path
+// params in path order, a params struct for query, header and cookie params,
+// and the request body, and return the decoded success response. Non-2xx
+// responses are returned as *APIError, carrying the decoded body when the
+// operation declares one for the status. Transport behavior (auth, retries,
+// tracing) plugs in as Middleware around the HTTP client.
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mborders/requiem/internal/specdoc"
+)
+
+// Options configures the generated package.
+type Options struct {
+	// Package is the generated package's name. Defaults to "client".
+	Package string
+}
+
+// Generate returns the source of a client package for the given OpenAPI
+// document (JSON or YAML). The output is gofmt-formatted.
+func Generate(spec []byte, opts Options) ([]byte, error) {
+	doc, err := specdoc.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Package == "" {
+		opts.Package = "client"
+	}
+
+	g := &generator{
+		doc:      doc,
+		typeName: map[string]string{},
+		used:     map[string]bool{},
+		imports:  map[string]bool{},
+	}
+	for name := range reservedNames {
+		g.used[name] = true
+	}
+	g.components()
+	g.operations()
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by requiem clientgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "// Package %s is a generated client for %s.\n", opts.Package, g.title())
+	fmt.Fprintf(&out, "package %s\n\n", opts.Package)
+	out.WriteString("import (\n")
+	for _, imp := range g.importList() {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n\n")
+	out.WriteString(runtimeSource)
+	out.Write(g.types.Bytes())
+	out.Write(g.methods.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated client: %w", err)
+	}
+	return src, nil
+}
+
+type obj = specdoc.Obj
+
+// reservedNames are declared by the runtime part of the generated package.
+var reservedNames = map[string]bool{
+	"Client": true, "Option": true, "New": true, "WithHTTPClient": true, "WithMiddleware": true,
+	"Doer": true, "DoerFunc": true, "Middleware": true, "APIError": true,
+}
+
+const refPrefix = "#/components/schemas/"
+
+type generator struct {
+	doc     obj
+	types   bytes.Buffer
+	methods bytes.Buffer
+
+	// typeName maps component schema names to their Go type names.
+	typeName map[string]string
+	used     map[string]bool
+	imports  map[string]bool
+}
+
+func (g *generator) title() string {
+	info, _ := g.doc["info"].(obj)
+	if t, _ := info["title"].(string); t != "" {
+		return t
+	}
+	return "an HTTP API"
+}
+
+func (g *generator) importList() []string {
+	list := []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "strings"}
+	for imp := range g.imports {
+		list = append(list, imp)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// uniqueName returns name, or name with a numeric suffix if it's taken.
+func (g *generator) uniqueName(name string) string {
+	candidate := name
+	for i := 2; g.used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	g.used[candidate] = true
+	return candidate
+}
+
+func (g *generator) schemas() obj {
+	components, _ := g.doc["components"].(obj)
+	schemas, _ := components["schemas"].(obj)
+	return schemas
+}
+
+// components declares a Go type for every component schema. Names are
+// assigned up front so schemas can reference each other in any order.
+func (g *generator) components() {
+	schemas := g.schemas()
+	names := specdoc.SortedKeys(schemas)
+	for _, name := range names {
+		g.typeName[name] = g.uniqueName(specdoc.ExportName(name))
+	}
+	for _, name := range names {
+		s, _ := schemas[name].(obj)
+		g.declare(g.typeName[name], s)
+	}
+}
+
+// declare emits a named type for schema s. Inline types it needs are
+// declared first, so its doc comment stays attached to it.
+func (g *generator) declare(name string, s obj) {
+	var decl string
+	switch {
+	case isObject(s) && s["properties"] != nil:
+		decl = g.structDecl(name, s)
+	case specdoc.SchemaType(s) == "string" && len(specdoc.List(s["enum"])) > 0:
+		decl = fmt.Sprintf("type %s string\n\nconst (\n", name)
+		for _, v := range specdoc.List(s["enum"]) {
+			constName := g.uniqueName(name + specdoc.ExportName(fmt.Sprint(v)))
+			decl += fmt.Sprintf("\t%s %s = %q\n", constName, name, fmt.Sprint(v))
+		}
+		decl += ")\n\n"
+	case s["oneOf"] != nil || s["anyOf"] != nil:
+		// Unions are left undecoded; unmarshal them into the variant named
+		// by their discriminator.
+		decl = fmt.Sprintf("type %s = json.RawMessage\n\n", name)
+	default:
+		decl = fmt.Sprintf("type %s %s\n\n", name, g.goType(s, name+"Item", false))
+	}
+	if d := specdoc.Str(s["description"]); d != "" {
+		fmt.Fprintf(&g.types, "// %s is %s\n", name, commentLine(lowerFirst(d)))
+	}
+	g.types.WriteString(decl)
+}
+
+func (g *generator) structDecl(name string, s obj) string {
+	props, _ := s["properties"].(obj)
+	required := map[string]bool{}
+	for _, r := range specdoc.List(s["required"]) {
+		required[fmt.Sprint(r)] = true
+	}
+
+	var fields bytes.Buffer
+	usedFields := map[string]bool{}
+	for _, prop := range specdoc.SortedKeys(props) {
+		ps, _ := props[prop].(obj)
+		field := specdoc.ExportName(prop)
+		for i := 2; usedFields[field]; i++ {
+			field = fmt.Sprintf("%s%d", specdoc.ExportName(prop), i)
+		}
+		usedFields[field] = true
+
+		typ := g.goType(ps, name+field, !required[prop])
+		tag := prop
+		if !required[prop] {
+			tag += ",omitempty"
+		}
+		if d := specdoc.Str(ps["description"]); d != "" {
+			fmt.Fprintf(&fields, "\t// %s\n", commentLine(d))
+		}
+		fmt.Fprintf(&fields, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+	return fmt.Sprintf("type %s struct {\n%s}\n\n", name, fields.String())
+}
+
+// goType returns the Go type for schema s. Inline objects are declared as
+// named types called hint. optional makes scalar and struct types pointers,
+// so absent values are distinguishable from zero ones.
+func (g *generator) goType(s obj, hint string, optional bool) string {
+	base, pointable := g.baseType(s, hint)
+	if pointable && (optional || specdoc.Nullable(s)) {
+		return "*" + base
+	}
+	return base
+}
+
+func (g *generator) baseType(s obj, hint string) (typ string, pointable bool) {
+	if s == nil {
+		return "json.RawMessage", false
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		return g.refType(ref)
+	}
+	if all := specdoc.List(s["allOf"]); len(all) == 1 {
+		inner, _ := all[0].(obj)
+		return g.baseType(inner, hint)
+	}
+	if s["oneOf"] != nil || s["anyOf"] != nil || s["allOf"] != nil {
+		return "json.RawMessage", false
+	}
+
+	switch specdoc.SchemaType(s) {
+	case "string":
+		switch specdoc.Str(s["format"]) {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time", true
+		case "byte":
+			return "[]byte", false
+		}
+		return "string", true
+	case "integer":
+		switch specdoc.Str(s["format"]) {
+		case "int32":
+			return "int32", true
+		case "uint64":
+			return "uint64", true
+		}
+		return "int64", true
+	case "number":
+		if specdoc.Str(s["format"]) == "float" {
+			return "float32", true
+		}
+		return "float64", true
+	case "boolean":
+		return "bool", true
+	case "array":
+		items, _ := s["items"].(obj)
+		return "[]" + g.goType(items, hint+"Item", false), false
+	case "object", "":
+		if s["properties"] != nil {
+			name := g.uniqueName(hint)
+			g.declare(name, s)
+			return name, true
+		}
+		if ap, ok := s["additionalProperties"].(obj); ok {
+			return "map[string]" + g.goType(ap, hint+"Value", false), false
+		}
+		if specdoc.SchemaType(s) == "object" {
+			return "map[string]interface{}", false
+		}
+	}
+	return "json.RawMessage", false
+}
+
+func (g *generator) refType(ref string) (string, bool) {
+	name, ok := g.typeName[strings.TrimPrefix(ref, refPrefix)]
+	if !strings.HasPrefix(ref, refPrefix) || !ok {
+		return "json.RawMessage", false
+	}
+	s, _ := g.schemas()[strings.TrimPrefix(ref, refPrefix)].(obj)
+	// Named slices, maps and unions are used as they are.
+	pointable := (isObject(s) && s["properties"] != nil) ||
+		(specdoc.SchemaType(s) != "array" && specdoc.SchemaType(s) != "object" && s["oneOf"] == nil && s["anyOf"] == nil)
+	return name, pointable
+}
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+type param struct {
+	name, in string
+	goName   string
+	typ      string
+	required bool
+	isSlice  bool
+}
+
+func (g *generator) operations() {
+	paths, _ := g.doc["paths"].(obj)
+	for _, path := range specdoc.SortedKeys(paths) {
+		item, _ := paths[path].(obj)
+		for _, m := range httpMethods {
+			op, ok := item[m].(obj)
+			if !ok {
+				continue
+			}
+			g.operation(path, m, item, op)
+		}
+	}
+}
+
+func (g *generator) operation(path, method string, item, op obj) {
+	id := specdoc.Str(op["operationId"])
+	if id == "" {
+		id = method + "_" + path
+	}
+	name := g.uniqueName(specdoc.ExportName(id))
+
+	// Path params are positional, in path order; the others go in a struct.
+	var pathParams, otherParams []param
+	declared := map[string]param{}
+	for _, raw := range append(specdoc.List(item["parameters"]), specdoc.List(op["parameters"])...) {
+		ps := g.resolve(raw)
+		if ps == nil {
+			continue
+		}
+		schema, _ := ps["schema"].(obj)
+		p := param{name: specdoc.Str(ps["name"]), in: specdoc.Str(ps["in"]), required: specdoc.Truthy(ps["required"])}
+		base, _ := g.baseType(schema, name+specdoc.ExportName(p.name))
+		p.isSlice = strings.HasPrefix(base, "[]")
+		p.typ = g.goType(schema, name+specdoc.ExportName(p.name), p.in != "path" && !p.required)
+		declared[p.in+" "+p.name] = p
+	}
+	used := map[string]bool{"ctx": true, "params": true, "body": true, "contentType": true, "out": true, "r": true, "err": true}
+	for _, seg := range pathParamNames(path) {
+		p, ok := declared["path "+seg]
+		if !ok {
+			p = param{name: seg, in: "path", typ: "string"}
+		}
+		p.goName = specdoc.LowerName(seg)
+		for i := 2; used[p.goName]; i++ {
+			p.goName = fmt.Sprintf("%s%d", specdoc.LowerName(seg), i)
+		}
+		used[p.goName] = true
+		pathParams = append(pathParams, p)
+	}
+	for _, key := range specdoc.SortedKeys(declared) {
+		if p := declared[key]; p.in != "path" {
+			otherParams = append(otherParams, p)
+		}
+	}
+
+	paramsType := ""
+	if len(otherParams) > 0 {
+		paramsType = g.uniqueName(name + "Params")
+		fmt.Fprintf(&g.types, "// %s holds the query, header and cookie parameters of %s.\ntype %s struct {\n", paramsType, name, paramsType)
+		fieldNames := map[string]bool{}
+		for i := range otherParams {
+			p := &otherParams[i]
+			p.goName = specdoc.ExportName(p.name)
+			for j := 2; fieldNames[p.goName]; j++ {
+				p.goName = fmt.Sprintf("%s%d", specdoc.ExportName(p.name), j)
+			}
+			fieldNames[p.goName] = true
+			fmt.Fprintf(&g.types, "\t%s %s // %s %s\n", p.goName, p.typ, p.in, p.name)
+		}
+		g.types.WriteString("}\n\n")
+	}
+
+	resultType, resultKind := g.successType(name, op)
+	failed := "err"
+	switch resultKind {
+	case resultPointer:
+		failed = "nil, err"
+	case resultValue:
+		failed = "*new(" + resultType + "), err"
+	}
+	bodyArg, bodyExpr := g.requestBody(name, op, failed)
+
+	// Signature.
+	w := &g.methods
+	fmt.Fprintf(w, "// %s calls %s %s.\n", name, strings.ToUpper(method), path)
+	for _, d := range []string{specdoc.Str(op["summary"]), specdoc.Str(op["description"])} {
+		if d != "" {
+			fmt.Fprintf(w, "//\n// %s\n", commentLine(d))
+		}
+	}
+	if specdoc.Truthy(op["deprecated"]) {
+		w.WriteString("//\n// Deprecated: this operation is deprecated by the API.\n")
+	}
+	args := []string{"ctx context.Context"}
+	for _, p := range pathParams {
+		args = append(args, p.goName+" "+p.typ)
+	}
+	if paramsType != "" {
+		args = append(args, "params *"+paramsType)
+	}
+	if bodyArg != "" {
+		args = append(args, bodyArg)
+	}
+	results := "error"
+	switch resultKind {
+	case resultPointer:
+		results = "(*" + resultType + ", error)"
+	case resultValue:
+		results = "(" + resultType + ", error)"
+	}
+	fmt.Fprintf(w, "func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), results)
+
+	// Request.
+	fmt.Fprintf(w, "\tr := request{method: %q, path: %s}\n", strings.ToUpper(method), pathExpr(path, pathParams))
+	if paramsType != "" {
+		fmt.Fprintf(w, "\tif params == nil {\n\t\tparams = &%s{}\n\t}\n", paramsType)
+		w.WriteString("\tr.query, r.header = url.Values{}, http.Header{}\n")
+		for _, p := range otherParams {
+			g.writeParam(w, p)
+		}
+	}
+	if bodyExpr != "" {
+		w.WriteString(bodyExpr)
+	}
+	if errs := g.errorTypes(op); errs != "" {
+		fmt.Fprintf(w, "\tr.errors = %s\n", errs)
+	}
+
+	// Call.
+	switch resultKind {
+	case resultPointer:
+		fmt.Fprintf(w, "\tout := new(%s)\n\tif err := c.do(ctx, r, out); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n", resultType)
+	case resultValue:
+		fmt.Fprintf(w, "\tvar out %s\n\terr := c.do(ctx, r, &out)\n\treturn out, err\n", resultType)
+	default:
+		w.WriteString("\treturn c.do(ctx, r, nil)\n")
+	}
+	w.WriteString("}\n\n")
+}
+
+func (g *generator) writeParam(w *bytes.Buffer, p param) {
+	field := "params." + p.goName
+	var set string
+	switch p.in {
+	case "query":
+		set = fmt.Sprintf("r.query.Add(%q, fmt.Sprint(%%s))", p.name)
+	case "header":
+		set = fmt.Sprintf("r.header.Add(%q, fmt.Sprint(%%s))", p.name)
+	case "cookie":
+		set = fmt.Sprintf("r.header.Add(\"Cookie\", (&http.Cookie{Name: %q, Value: fmt.Sprint(%%s)}).String())", p.name)
+	default:
+		return
+	}
+	switch {
+	case p.isSlice:
+		fmt.Fprintf(w, "\tfor _, v := range %s {\n\t\t%s\n\t}\n", field, fmt.Sprintf(set, "v"))
+	case strings.HasPrefix(p.typ, "*"):
+		fmt.Fprintf(w, "\tif %s != nil {\n\t\t%s\n\t}\n", field, fmt.Sprintf(set, "*"+field))
+	default:
+		fmt.Fprintf(w, "\t%s\n", fmt.Sprintf(set, field))
+	}
+}
+
+// requestBody returns the body argument of an operation and the statements
+// that attach it to the request. JSON bodies are typed; other media types
+// take a reader and its content type.
+func (g *generator) requestBody(name string, op obj, failed string) (arg, expr string) {
+	rb := g.resolve(op["requestBody"])
+	if rb == nil {
+		return "", ""
+	}
+	content, _ := rb["content"].(obj)
+	for _, mt := range specdoc.SortedKeys(content) {
+		if isJSON(mt) {
+			media, _ := content[mt].(obj)
+			schema, _ := media["schema"].(obj)
+			typ := g.goType(schema, name+"Body", false)
+			return "body " + typ, fmt.Sprintf("\tb, err := json.Marshal(body)\n\tif err != nil {\n\t\treturn %s\n\t}\n\tr.body, r.contentType = bytes.NewReader(b), %q\n", failed, mt)
+		}
+	}
+	return "body io.Reader, contentType string", "\tr.body, r.contentType = body, contentType\n"
+}
+
+type resultKind int
+
+const (
+	resultNone resultKind = iota
+	resultPointer
+	resultValue
+)
+
+// successType returns the decoded type of the operation's first 2xx response.
+func (g *generator) successType(name string, op obj) (string, resultKind) {
+	responses, _ := op["responses"].(obj)
+	for _, code := range specdoc.SortedKeys(responses) {
+		if !strings.HasPrefix(code, "2") {
+			continue
+		}
+		resp := g.resolve(responses[code])
+		content, _ := resp["content"].(obj)
+		if len(content) == 0 {
+			continue
+		}
+		for _, mt := range specdoc.SortedKeys(content) {
+			if !isJSON(mt) {
+				continue
+			}
+			media, _ := content[mt].(obj)
+			schema, _ := media["schema"].(obj)
+			typ := g.goType(schema, name+"Response", false)
+			if g.isStruct(schema) {
+				return strings.TrimPrefix(typ, "*"), resultPointer
+			}
+			return typ, resultValue
+		}
+		return "[]byte", resultValue
+	}
+	return "", resultNone
+}
+
+// errorTypes returns a map literal of constructors for the declared JSON
+// bodies of non-2xx responses, keyed by status (0 for "default").
+func (g *generator) errorTypes(op obj) string {
+	responses, _ := op["responses"].(obj)
+	var entries []string
+	for _, code := range specdoc.SortedKeys(responses) {
+		if strings.HasPrefix(code, "2") {
+			continue
+		}
+		status := code
+		if code == "default" {
+			status = "0"
+		} else if len(code) != 3 || strings.Trim(code, "0123456789") != "" {
+			continue
+		}
+		resp := g.resolve(responses[code])
+		content, _ := resp["content"].(obj)
+		for _, mt := range specdoc.SortedKeys(content) {
+			if !isJSON(mt) {
+				continue
+			}
+			media, _ := content[mt].(obj)
+			schema, _ := media["schema"].(obj)
+			ref, _ := schema["$ref"].(string)
+			if typ, ok := g.typeName[strings.TrimPrefix(ref, refPrefix)]; ok && strings.HasPrefix(ref, refPrefix) {
+				entries = append(entries, fmt.Sprintf("%s: func() interface{} { return new(%s) }", status, typ))
+			}
+			break
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	return "map[int]func() interface{}{\n\t\t" + strings.Join(entries, ",\n\t\t") + ",\n\t}"
+}
+
+func (g *generator) resolve(v interface{}) obj {
+	o, _ := v.(obj)
+	for i := 0; o != nil && i < 32; i++ {
+		ref, ok := o["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return o
+		}
+		var cur interface{} = g.doc
+		for _, part := range strings.Split(ref[2:], "/") {
+			m, _ := cur.(obj)
+			cur = m[part]
+		}
+		o, _ = cur.(obj)
+	}
+	return o
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+func pathParamNames(path string) []string {
+	var names []string
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// pathExpr builds the Go expression for a request path, escaping params.
+func pathExpr(path string, params []param) string {
+	if len(params) == 0 {
+		return fmt.Sprintf("%q", path)
+	}
+	byName := map[string]param{}
+	for _, p := range params {
+		byName[p.name] = p
+	}
+	var parts []string
+	last := 0
+	for _, loc := range pathParamPattern.FindAllStringSubmatchIndex(path, -1) {
+		if loc[0] > last {
+			parts = append(parts, fmt.Sprintf("%q", path[last:loc[0]]))
+		}
+		p := byName[path[loc[2]:loc[3]]]
+		parts = append(parts, fmt.Sprintf("url.PathEscape(fmt.Sprint(%s))", p.goName))
+		last = loc[1]
+	}
+	if last < len(path) {
+		parts = append(parts, fmt.Sprintf("%q", path[last:]))
+	}
+	return strings.Join(parts, " + ")
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	if len(r) > 1 && unicode.IsUpper(r[0]) && !unicode.IsUpper(r[1]) {
+		r[0] = unicode.ToLower(r[0])
+	}
+	return string(r)
+}
+
+func commentLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func isJSON(mt string) bool {
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// isStruct reports whether s is generated as a struct type.
+func (g *generator) isStruct(s obj) bool {
+	if all := specdoc.List(s["allOf"]); len(all) == 1 {
+		s, _ = all[0].(obj)
+	}
+	s = g.resolve(s)
+	return s != nil && isObject(s) && s["properties"] != nil && s["oneOf"] == nil && s["anyOf"] == nil
+}
+
+func isObject(s obj) bool {
+	t := specdoc.SchemaType(s)
+	return t == "object" || t == ""
+}
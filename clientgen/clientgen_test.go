@@ -0,0 +1,123 @@
+package clientgen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+	"time"
+
+	"github.com/mborders/requiem"
+	"github.com/stretchr/testify/assert"
+)
+
+type Gadget struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name" validate:"required"`
+	Weight    *float64  `json:"weight"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	Parts     []Part    `json:"parts"`
+	Units     uint64    `json:"units"`
+}
+
+type Part struct {
+	Serial int32 `json:"serial"`
+}
+
+type NewGadget struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type Problem struct {
+	Message string `json:"message"`
+}
+
+type gadgetController struct{}
+
+func (gadgetController) Load(router *requiem.Router) {
+	r := router.NewRestRouter("/gadgets")
+	r.Get("/", func(ctx requiem.HTTPContext) {}).
+		Summary("List gadgets").
+		Query("limit", "integer", false, "Max items").
+		Header("X-Tenant", "string", true, "Tenant").
+		Returns(200, []Gadget{}, "Gadgets")
+	r.Get("/{id}", func(ctx requiem.HTTPContext) {}).
+		OperationID("getGadget").
+		Param("id", "string", "Gadget ID").
+		Returns(200, Gadget{}, "The gadget").
+		Returns(404, Problem{}, "Not found")
+	r.Post("/", func(ctx requiem.HTTPContext) {}, NewGadget{}).
+		Returns(201, Gadget{}, "Created")
+	r.Delete("/{id}", func(ctx requiem.HTTPContext) {}, nil).
+		Param("id", "string", "Gadget ID").
+		Returns(204, nil, "Deleted")
+	r.Get("/{id}/export", func(ctx requiem.HTTPContext) {}).
+		Param("id", "string", "Gadget ID").
+		Produces("text/csv").
+		Returns(200, nil, "CSV export")
+}
+
+func gadgetSpec() []byte {
+	s := requiem.NewServer(gadgetController{})
+	s.UseOpenAPI(requiem.OpenAPIConfig{Title: "Gadget API", Version: "1"})
+	return s.GetOpenAPISpec()
+}
+
+func TestGenerate_TypeChecks(t *testing.T) {
+	src, err := Generate(gadgetSpec(), Options{Package: "gadgets"})
+	assert.NoError(t, err)
+
+	typeCheck(t, "gadgets", src)
+
+	code := string(src)
+	assert.Contains(t, code, "// Code generated by requiem clientgen. DO NOT EDIT.")
+	assert.Contains(t, code, "func (c *Client) GetGadgets(ctx context.Context, params *GetGadgetsParams) ([]Gadget, error)")
+	assert.Contains(t, code, "func (c *Client) GetGadget(ctx context.Context, id string) (*Gadget, error)")
+	assert.Contains(t, code, "func (c *Client) PostGadgets(ctx context.Context, body NewGadget) (*Gadget, error)")
+	assert.Contains(t, code, "func (c *Client) DeleteGadgetsID(ctx context.Context, id string) error")
+	assert.Contains(t, code, "func (c *Client) GetGadgetsIDExport(ctx context.Context, id string) ([]byte, error)")
+	assert.Contains(t, code, "404: func() interface{} { return new(Problem) }")
+	assert.Contains(t, code, "Weight    *float64   `json:\"weight,omitempty\"`")
+	assert.Contains(t, code, "CreatedAt *time.Time `json:\"created_at,omitempty\"`")
+	assert.Contains(t, code, "Name      string     `json:\"name\"`")
+	assert.Contains(t, code, "Units     *uint64    `json:\"units,omitempty\"`")
+	assert.Contains(t, code, "Serial *int32 `json:\"serial,omitempty\"`")
+	assert.Contains(t, code, "Limit   *int64 // query limit")
+	assert.Contains(t, code, "XTenant string // header X-Tenant")
+}
+
+func TestGenerate_RejectsNonOpenAPI(t *testing.T) {
+	_, err := Generate([]byte(`{"swagger": "2.0"}`), Options{})
+	assert.EqualError(t, err, "not an OpenAPI document")
+}
+
+func TestGenerate_EnumConstantNames(t *testing.T) {
+	spec := `{"openapi": "3.0.3", "info": {"title": "Enums", "version": "1"}, "paths": {}, "components": {"schemas": {
+		"Mode": {"type": "string", "enum": ["a-b", "a_b"]},
+		"Color": {"type": "string", "enum": ["red"]},
+		"ColorRed": {"type": "object", "properties": {"hex": {"type": "string"}}}}}}`
+	src, err := Generate([]byte(spec), Options{})
+	assert.NoError(t, err)
+
+	typeCheck(t, "client", src)
+
+	code := string(src)
+	assert.Contains(t, code, `ModeAB  Mode = "a-b"`)
+	assert.Contains(t, code, `ModeAB2 Mode = "a_b"`)
+	assert.Contains(t, code, `ColorRed2 Color = "red"`)
+	assert.Contains(t, code, "type ColorRed struct")
+}
+
+// typeCheck fails the test if the generated package doesn't compile.
+func typeCheck(t *testing.T, pkg string, src []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "client_gen.go", src, parser.ParseComments)
+	assert.NoError(t, err)
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check(pkg, fset, []*ast.File{file}, nil)
+	assert.NoError(t, err, string(src))
+}
@@ -0,0 +1,136 @@
+package clientgen
+
+// runtimeSource is the operation-independent part of every generated client.
+const runtimeSource = `// Doer sends HTTP requests. *http.Client implements it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to a Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the transport of a Client, e.g. to add auth headers,
+// retries or tracing.
+type Middleware func(next Doer) Doer
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sends requests through d instead of http.DefaultClient.
+func WithHTTPClient(d Doer) Option {
+	return func(c *Client) {
+		c.doer = d
+	}
+}
+
+// WithMiddleware wraps the transport in the given middleware. The first one
+// sees each request first.
+func WithMiddleware(m ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, m...)
+	}
+}
+
+// Client calls the API. Create one with New; it is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	doer       Doer
+	middleware []Middleware
+}
+
+// New returns a Client for the API served at baseURL, which includes any base
+// path, e.g. "https://widgets.example.com/api".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{baseURL: strings.TrimRight(baseURL, "/"), doer: http.DefaultClient}
+	for _, o := range opts {
+		o(c)
+	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		c.doer = c.middleware[i](c.doer)
+	}
+	return c
+}
+
+// APIError is returned for responses with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	Body       []byte
+	// Value is the decoded body when the operation declares a JSON body for
+	// the status, as a pointer to the declared type.
+	Value interface{}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, bytes.TrimSpace(e.Body))
+}
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	body        io.Reader
+	contentType string
+	// errors builds the declared body type for a non-2xx status, or for any
+	// other status under 0.
+	errors map[int]func() interface{}
+}
+
+func (c *Client) do(ctx context.Context, r request, out interface{}) error {
+	u := c.baseURL + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u, r.body)
+	if err != nil {
+		return err
+	}
+	for k, v := range r.header {
+		req.Header[k] = v
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+
+	res, err := c.doer.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: res.StatusCode, Body: data}
+		newValue, ok := r.errors[res.StatusCode]
+		if !ok {
+			newValue = r.errors[0]
+		}
+		if newValue != nil && len(data) > 0 {
+			if v := newValue(); json.Unmarshal(data, v) == nil {
+				apiErr.Value = v
+			}
+		}
+		return apiErr
+	}
+
+	switch o := out.(type) {
+	case nil:
+	case *[]byte:
+		*o = data
+	default:
+		if len(data) > 0 {
+			return json.Unmarshal(data, out)
+		}
+	}
+	return nil
+}
+
+`
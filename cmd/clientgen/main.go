@@ -0,0 +1,55 @@
+// Command clientgen generates a typed Go client package from an OpenAPI
+// document:
+//
+//	clientgen -spec openapi.json -package widgets -o client_gen.go
+//
+// It is meant for go:generate, next to a spec written with requiem's
+// Server.GetOpenAPISpec:
+//
+//	//go:generate go run github.com/mborders/requiem/cmd/clientgen -spec ../api/openapi.json -package widgets -o client_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mborders/requiem/clientgen"
+)
+
+func main() {
+	specPath := flag.String("spec", "-", "OpenAPI document (JSON or YAML), - for stdin")
+	pkg := flag.String("package", "client", "name of the generated package")
+	outPath := flag.String("o", "-", "output file, - for stdout")
+	flag.Parse()
+
+	var spec []byte
+	var err error
+	if *specPath == "-" {
+		spec, err = io.ReadAll(os.Stdin)
+	} else {
+		spec, err = os.ReadFile(*specPath)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	src, err := clientgen.Generate(spec, clientgen.Options{Package: *pkg})
+	if err != nil {
+		fail(err)
+	}
+	if *outPath == "-" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*outPath, src, 0o644)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "clientgen:", err)
+	os.Exit(1)
+}
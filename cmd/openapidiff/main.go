@@ -0,0 +1,68 @@
+// Command openapidiff compares an OpenAPI document with a baseline and exits
+// non-zero when the revision contains breaking changes:
+//
+//	openapidiff [-json] [-breaking-only] baseline.json current.json
+//
+// Both documents may be JSON or YAML. Exit status is 0 when there are no
+// breaking changes, 1 when there are, and 2 on usage or read errors.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mborders/requiem/openapidiff"
+)
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	breakingOnly := flag.Bool("breaking-only", false, "only print breaking changes")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: openapidiff [flags] <baseline> <current>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	base, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	current, err := os.ReadFile(flag.Arg(1))
+	if err != nil {
+		fail(err)
+	}
+	report, err := openapidiff.Compare(base, current)
+	if err != nil {
+		fail(err)
+	}
+
+	changes := report.Changes
+	if *breakingOnly {
+		changes = report.Breaking()
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(openapidiff.Report{Changes: changes})
+	} else {
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+		fmt.Printf("%d change(s), %d breaking\n", len(report.Changes), len(report.Breaking()))
+	}
+
+	if report.HasBreaking() {
+		os.Exit(1)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "openapidiff:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,101 @@
+// Command openapimerge combines the OpenAPI documents of several services
+// into one:
+//
+//	openapimerge [flags] name[:prefix]=file ...
+//
+// Each argument names a service and its document (JSON or YAML), optionally
+// with the gateway path its routes are mounted under, e.g.
+// "billing:/billing=billing.json". The combined JSON document is written to
+// -o, or stdout.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mborders/requiem/openapimerge"
+	"gopkg.in/yaml.v3"
+)
+
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func main() {
+	var opts openapimerge.Options
+	var servers stringList
+	flag.StringVar(&opts.Title, "title", "API", "title of the combined document")
+	flag.StringVar(&opts.Version, "version", "1.0.0", "version of the combined document")
+	flag.StringVar(&opts.Description, "description", "", "description of the combined document")
+	flag.Var(&servers, "server", "server URL of the combined document (repeatable)")
+	flag.BoolVar(&opts.Namespace, "namespace", false, "prefix every component with its service's name")
+	out := flag.String("o", "", "output file (default stdout)")
+	asYAML := flag.Bool("yaml", false, "write YAML instead of JSON")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: openapimerge [flags] name[:prefix]=file ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.Servers = servers
+
+	services := make([]openapimerge.Service, 0, flag.NArg())
+	for _, arg := range flag.Args() {
+		svc, file := parseArg(arg)
+		spec, err := os.ReadFile(file)
+		if err != nil {
+			fail(err)
+		}
+		svc.Spec = spec
+		services = append(services, svc)
+	}
+
+	doc, err := openapimerge.Merge(services, opts)
+	if err != nil {
+		fail(err)
+	}
+	if *asYAML {
+		var v interface{}
+		if err := yaml.Unmarshal(doc, &v); err != nil {
+			fail(err)
+		}
+		if doc, err = yaml.Marshal(v); err != nil {
+			fail(err)
+		}
+	}
+	if *out == "" {
+		os.Stdout.Write(doc)
+		return
+	}
+	if err := os.WriteFile(*out, doc, 0o644); err != nil {
+		fail(err)
+	}
+}
+
+// parseArg splits "name[:prefix]=file". Without "=", the service is named
+// after the file.
+func parseArg(arg string) (openapimerge.Service, string) {
+	spec, file, ok := strings.Cut(arg, "=")
+	if !ok {
+		file = arg
+		spec = strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg))
+	}
+	name, prefix, _ := strings.Cut(spec, ":")
+	return openapimerge.Service{Name: name, PathPrefix: prefix}, file
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "openapimerge:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,274 @@
+package requiem
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Media types with built-in request body binding.
+const (
+	MediaTypeJSON      = "application/json"
+	MediaTypeForm      = "application/x-www-form-urlencoded"
+	MediaTypeMultipart = "multipart/form-data"
+)
+
+// MultipartMaxMemory is how much of a multipart body is held in memory while
+// binding; larger uploads are spooled to temporary files, which are removed
+// when the request finishes.
+var MultipartMaxMemory int64 = 32 << 20
+
+var (
+	fileHeaderType = reflect.TypeOf(multipart.FileHeader{})
+
+	errUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// Consumes declares the request body media types the route accepts. Bodies of
+// JSON (the default), urlencoded form and multipart form types are bound into
+// the route's body struct; requests with a Content-Type that is neither
+// declared nor JSON are rejected with 415. On routes without a body struct,
+// read the body yourself, e.g. with HTTPContext.MultipartReader to stream
+// large uploads.
+func (rt *Route) Consumes(mediaTypes ...string) *Route {
+	rt.consumes = append(rt.consumes, mediaTypes...)
+	return rt
+}
+
+// Produces declares the media types the route's responses are served as, e.g.
+// "text/csv" or "application/pdf". Each is listed in the spec for every
+// response; responses declared with a nil type get a string or binary schema.
+func (rt *Route) Produces(mediaTypes ...string) *Route {
+	rt.produces = append(rt.produces, mediaTypes...)
+	return rt
+}
+
+// FormFile returns the first uploaded file for the given multipart form key.
+func (ctx *HTTPContext) FormFile(key string) (multipart.File, *multipart.FileHeader, error) {
+	return ctx.Request.FormFile(key)
+}
+
+// MultipartReader streams a multipart request body part by part without
+// buffering it. Only usable on routes registered without a body struct, as
+// binding consumes the body.
+func (ctx *HTTPContext) MultipartReader() (*multipart.Reader, error) {
+	return ctx.Request.MultipartReader()
+}
+
+// SendData writes a raw response body with the given content type and status.
+func (ctx *HTTPContext) SendData(data []byte, contentType string, s int) {
+	ctx.Response.Header().Set("Content-Type", contentType)
+	ctx.Response.WriteHeader(s)
+	ctx.Response.Write(data)
+}
+
+// SendStream copies r to the response with the given content type and status.
+func (ctx *HTTPContext) SendStream(r io.Reader, contentType string, s int) error {
+	ctx.Response.Header().Set("Content-Type", contentType)
+	ctx.Response.WriteHeader(s)
+	_, err := io.Copy(ctx.Response, r)
+	return err
+}
+
+func isJSONMediaType(mt string) bool {
+	return mt == MediaTypeJSON || strings.HasSuffix(mt, "+json")
+}
+
+func isFormMediaType(mt string) bool {
+	return mt == MediaTypeForm || mt == MediaTypeMultipart
+}
+
+// readBody decodes a request body into a new value of v's type according to
+// its Content-Type.
+func readBody(r *http.Request, rt *Route, v interface{}) (interface{}, error) {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	// JSON is always accepted so MCP tool calls, which send JSON, keep working.
+	if mt != "" && !isJSONMediaType(mt) && len(rt.consumes) > 0 {
+		accepted := false
+		for _, c := range rt.consumes {
+			if c == mt {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return nil, errUnsupportedMediaType
+		}
+	}
+	if isFormMediaType(mt) {
+		return readFormBody(r, v)
+	}
+	return readJSONBody(r.Body, v)
+}
+
+func readFormBody(r *http.Request, v interface{}) (interface{}, error) {
+	o := reflect.New(reflect.TypeOf(v))
+	var files map[string][]*multipart.FileHeader
+	if strings.HasPrefix(r.Header.Get("Content-Type"), MediaTypeMultipart) {
+		if err := r.ParseMultipartForm(MultipartMaxMemory); err != nil {
+			return o.Interface(), err
+		}
+		files = r.MultipartForm.File
+	} else if err := r.ParseForm(); err != nil {
+		return o.Interface(), err
+	}
+	return o.Interface(), bindForm(r.Form, files, o.Elem())
+}
+
+// formFieldName returns the form key of a struct field: its form tag, else its
+// json name, else the field name. ok is false for skipped fields.
+func formFieldName(f reflect.StructField) (name string, ok bool) {
+	tag := f.Tag.Get("form")
+	if tag == "" {
+		tag = f.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", false
+	}
+	if n := strings.Split(tag, ",")[0]; n != "" {
+		return n, true
+	}
+	return f.Name, true
+}
+
+// bindForm sets v's fields from form values and uploaded files.
+func bindForm(values url.Values, files map[string][]*multipart.FileHeader, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous && f.Tag.Get("form") == "" && f.Tag.Get("json") == "" && f.Type.Kind() == reflect.Struct {
+			if err := bindForm(values, files, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		name, ok := formFieldName(f)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case f.Type == reflect.PointerTo(fileHeaderType):
+			if fh := files[name]; len(fh) > 0 {
+				fv.Set(reflect.ValueOf(fh[0]))
+			}
+		case f.Type == reflect.TypeOf([]*multipart.FileHeader{}):
+			if fh := files[name]; len(fh) > 0 {
+				fv.Set(reflect.ValueOf(fh))
+			}
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() != reflect.Uint8:
+			vals, ok := values[name]
+			if !ok {
+				continue
+			}
+			s := reflect.MakeSlice(f.Type, len(vals), len(vals))
+			for j, val := range vals {
+				if err := setFormValue(s.Index(j), val); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+			fv.Set(s)
+		default:
+			if _, ok := values[name]; !ok {
+				continue
+			}
+			if err := setFormValue(fv, values.Get(name)); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func setFormValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setFormValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if v.Type() == timeType {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported form field type %s", v.Type())
+	}
+	return nil
+}
+
+// mediaSchema documents a body of type t served as media type mt.
+func (db *docBuilder) mediaSchema(mt string, t reflect.Type) map[string]interface{} {
+	switch {
+	case t != nil && isJSONMediaType(mt):
+		return db.schemaFor(t)
+	case t != nil && isFormMediaType(mt):
+		return db.formSchema(t)
+	case mt == MediaTypeMultipart, mt == MediaTypeForm:
+		return map[string]interface{}{"type": "object"}
+	case strings.HasPrefix(mt, "text/"):
+		return map[string]interface{}{"type": "string"}
+	}
+	return map[string]interface{}{"type": "string", "format": "binary"}
+}
+
+// formSchema documents a form-bound struct inline, keyed by form field names.
+func (db *docBuilder) formSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return db.schemaFor(t)
+	}
+	return db.buildObjectSchema(t, "form")
+}
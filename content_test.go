@@ -0,0 +1,133 @@
+package requiem
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type UploadForm struct {
+	Title  string                  `json:"title" validate:"required"`
+	Count  int                     `form:"n"`
+	Tags   []string                `json:"tags"`
+	Public *bool                   `json:"public"`
+	File   *multipart.FileHeader   `json:"file"`
+	Extras []*multipart.FileHeader `json:"extras"`
+}
+
+type uploadController struct {
+	got chan *UploadForm
+}
+
+func (c uploadController) Load(router *Router) {
+	r := router.NewRestRouter("/uploads")
+	r.Post("/", func(ctx HTTPContext) {
+		c.got <- ctx.Body.(*UploadForm)
+		ctx.SendStatus(http.StatusCreated)
+	}, UploadForm{}).
+		Consumes(MediaTypeMultipart, MediaTypeForm).
+		Returns(http.StatusCreated, nil, "Created")
+
+	r.Get("/report", func(ctx HTTPContext) {
+		ctx.SendData([]byte("a,b\n1,2\n"), "text/csv", http.StatusOK)
+	}).
+		Produces("text/csv", "application/pdf").
+		Returns(http.StatusOK, nil, "Report")
+}
+
+func TestContent_MultipartBinding(t *testing.T) {
+	c := uploadController{got: make(chan *UploadForm, 1)}
+	router := newRouter("/api", nil, []IHttpController{c})
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "Report")
+	mw.WriteField("n", "3")
+	mw.WriteField("tags", "a")
+	mw.WriteField("tags", "b")
+	mw.WriteField("public", "true")
+	fw, _ := mw.CreateFormFile("file", "report.txt")
+	fw.Write([]byte("hello"))
+	mw.CreateFormFile("extras", "1.txt")
+	mw.CreateFormFile("extras", "2.txt")
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads/", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := serveRequest(router, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	form := <-c.got
+	assert.Equal(t, "Report", form.Title)
+	assert.Equal(t, 3, form.Count)
+	assert.Equal(t, []string{"a", "b"}, form.Tags)
+	assert.True(t, *form.Public)
+	assert.Equal(t, "report.txt", form.File.Filename)
+	f, _ := form.File.Open()
+	data, _ := io.ReadAll(f)
+	assert.Equal(t, "hello", string(data))
+	assert.Len(t, form.Extras, 2)
+}
+
+func TestContent_URLEncodedAndJSONBinding(t *testing.T) {
+	c := uploadController{got: make(chan *UploadForm, 1)}
+	router := newRouter("/api", nil, []IHttpController{c})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads/", strings.NewReader(url.Values{"title": {"Hi"}, "n": {"7"}}.Encode()))
+	req.Header.Set("Content-Type", MediaTypeForm)
+	rec := serveRequest(router, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, 7, (<-c.got).Count)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/uploads/", strings.NewReader(url.Values{"title": {"Hi"}, "n": {"x"}}.Encode()))
+	req.Header.Set("Content-Type", MediaTypeForm)
+	rec = serveRequest(router, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/uploads/", strings.NewReader(`{"title": "Hi"}`))
+	req.Header.Set("Content-Type", MediaTypeJSON)
+	rec = serveRequest(router, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "Hi", (<-c.got).Title)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/uploads/", strings.NewReader("Hi"))
+	req.Header.Set("Content-Type", "text/plain")
+	rec = serveRequest(router, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
+
+func TestContent_MediaTypesInSpec(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{uploadController{}})
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buildDoc(OpenAPIConfig{Title: "T", Version: "1"}, router.routes), &spec))
+	paths := spec["paths"].(map[string]interface{})
+
+	post := paths["/uploads/"].(map[string]interface{})["post"].(map[string]interface{})
+	content := post["requestBody"].(map[string]interface{})["content"].(map[string]interface{})
+	assert.NotContains(t, content, MediaTypeJSON)
+	props := content[MediaTypeMultipart].(map[string]interface{})["schema"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "binary", "nullable": true}, props["file"])
+	assert.Equal(t, "binary", props["extras"].(map[string]interface{})["items"].(map[string]interface{})["format"])
+	assert.Contains(t, props, "n")
+	assert.Contains(t, content, MediaTypeForm)
+
+	get := paths["/uploads/report"].(map[string]interface{})["get"].(map[string]interface{})
+	resp := get["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}, resp["text/csv"])
+	assert.Equal(t, map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}}, resp["application/pdf"])
+}
+
+func TestContent_SendData(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{uploadController{}})
+	rec := serve(router, http.MethodGet, "/api/uploads/report", "", nil)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "a,b\n1,2\n", rec.Body.String())
+}
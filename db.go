@@ -2,6 +2,8 @@ package requiem
 
 import (
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/caarlos0/env"
 	"gorm.io/driver/postgres"
@@ -10,17 +12,25 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-type dbConfig struct {
+// DBConfig holds Postgres connection settings. UsePostgresDB loads it from
+// environment variables; UsePostgresDBWithConfig accepts one directly.
+type DBConfig struct {
 	Host         string `env:"DB_HOST"`
 	Port         string `env:"DB_PORT"`
 	Username     string `env:"DB_USERNAME"`
 	Password     string `env:"DB_PASSWORD"`
 	DatabaseName string `env:"DB_NAME"`
 	SSLMode      string `env:"DB_SSL_MODE" envDefault:"disable"`
+	// ReplicaHosts lists read replicas as "host" or "host:port" (Port is used
+	// when omitted). Replicas share the primary's credentials and database name.
+	ReplicaHosts []string `env:"DB_REPLICA_HOSTS" envSeparator:","`
+	// ReplicaHealthInterval is how often replicas are pinged while the server
+	// runs. Unhealthy replicas are skipped until they recover.
+	ReplicaHealthInterval time.Duration `env:"DB_REPLICA_HEALTH_INTERVAL" envDefault:"10s"`
 }
 
-func loadDBConfig() dbConfig {
-	cfg := dbConfig{}
+func loadDBConfig() DBConfig {
+	cfg := DBConfig{}
 	err := env.Parse(&cfg)
 	if err != nil {
 		Logger.Fatal("Could not load DB config: %s", err.Error())
@@ -29,6 +39,11 @@ func loadDBConfig() dbConfig {
 	return cfg
 }
 
+func (cfg DBConfig) dsn(host, port string) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		host, port, cfg.Username, cfg.Password, cfg.DatabaseName, cfg.SSLMode)
+}
+
 func buildGormConfig(debugMode bool) *gorm.Config {
 	c := &gorm.Config{}
 	if debugMode {
@@ -40,18 +55,35 @@ func buildGormConfig(debugMode bool) *gorm.Config {
 	return c
 }
 
-// newPostgresDBConnection obtains DB connection details from environment variables
-// and initializes a Postgres DB connection.
-func newPostgresDBConnection(debugMode bool) *gorm.DB {
-	cfg := loadDBConfig()
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.Username, cfg.Password, cfg.DatabaseName, cfg.SSLMode)
-
-	db, err := gorm.Open(postgres.Open(dsn), buildGormConfig(debugMode))
+// newPostgresDBConnection initializes a Postgres DB connection from the given
+// config. When replicas are configured, they are opened too and attached to the
+// primary as a replica router (see replica.go).
+func newPostgresDBConnection(cfg DBConfig, debugMode bool) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(cfg.dsn(cfg.Host, cfg.Port)), buildGormConfig(debugMode))
 	if err != nil {
 		Logger.Fatal("Could not connect to DB %s", err)
 	}
 
+	if len(cfg.ReplicaHosts) == 0 {
+		return db
+	}
+
+	replicas := map[string]*gorm.DB{}
+	for _, h := range cfg.ReplicaHosts {
+		host, port := h, cfg.Port
+		if hh, pp, err := net.SplitHostPort(h); err == nil {
+			host, port = hh, pp
+		}
+		rdb, err := gorm.Open(postgres.Open(cfg.dsn(host, port)), buildGormConfig(debugMode))
+		if err != nil {
+			Logger.Fatal("Could not connect to DB replica %s: %s", h, err)
+		}
+		replicas[h] = rdb
+	}
+	if err := db.Use(newReplicaRouter(replicas, cfg.ReplicaHealthInterval)); err != nil {
+		Logger.Fatal("Could not register DB replicas: %s", err)
+	}
+
 	return db
 }
 
@@ -0,0 +1,229 @@
+package requiem
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+//go:generate sh docsui/fetch.sh
+
+//go:embed docsui
+var docsUIFiles embed.FS
+
+var docsPageTemplate = template.Must(template.ParseFS(docsUIFiles, "docsui/index.html"))
+
+// DocsUI selects the renderer of the docs page.
+type DocsUI string
+
+const (
+	DocsUISwagger DocsUI = "swagger"
+	DocsUIReDoc   DocsUI = "redoc"
+)
+
+// DocsTheme selects the color scheme of the docs page.
+type DocsTheme string
+
+const (
+	DocsThemeLight DocsTheme = "light"
+	DocsThemeDark  DocsTheme = "dark"
+)
+
+// Vendor files the docs page loads for each renderer, relative to
+// DocsConfig.Assets.
+var docsVendorFiles = map[DocsUI][]string{
+	DocsUISwagger: {"swagger-ui.css", "swagger-ui-bundle.js"},
+	DocsUIReDoc:   {"redoc.standalone.js"},
+}
+
+var docsCDNFiles = map[string]string{
+	"swagger-ui.css":       "https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui.css",
+	"swagger-ui-bundle.js": "https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui-bundle.js",
+	"redoc.standalone.js":  "https://unpkg.com/redoc@2.1.5/bundles/redoc.standalone.js",
+}
+
+const docsCDNOrigin = "https://unpkg.com"
+
+// DocsConfig configures the page served at OpenAPIConfig.DocsPath.
+type DocsConfig struct {
+	// UI is the renderer: DocsUISwagger (default) or DocsUIReDoc.
+	UI DocsUI
+	// CDN loads the renderer from unpkg.com, at the versions pinned in
+	// docsui/fetch.sh, instead of serving it from under DocsPath.
+	CDN bool
+	// Assets holds the renderer's files (swagger-ui.css and
+	// swagger-ui-bundle.js, or redoc.standalone.js) at its root. Defaults to
+	// the bundles embedded from docsui/vendor, which don't include ReDoc yet.
+	// Missing files are fatal unless CDN is set.
+	Assets fs.FS
+	// Theme is DocsThemeLight (default) or DocsThemeDark.
+	Theme DocsTheme
+	// LogoURL is shown in a header above the docs. Its origin is allowed by
+	// the page's Content-Security-Policy.
+	LogoURL string
+	// CSP replaces the default Content-Security-Policy of the page.
+	CSP string
+}
+
+type docsPage struct {
+	Title   string
+	SpecURL string
+	UI      DocsUI
+	Theme   DocsTheme
+	LogoURL string
+	Styles  []string
+	Scripts []string
+}
+
+// mountDocs serves the docs page at docsPath and its assets under
+// docsPath/assets/.
+func (c *openapiController) mountDocs(router *Router, specPath, docsPath string) {
+	cfg := c.cfg.Docs
+	if cfg.UI == "" {
+		cfg.UI = DocsUISwagger
+	}
+	if cfg.Theme == "" {
+		cfg.Theme = DocsThemeLight
+	}
+	if cfg.Assets == nil {
+		cfg.Assets, _ = fs.Sub(docsUIFiles, "docsui/vendor")
+	}
+	if !cfg.CDN {
+		for _, name := range docsVendorFiles[cfg.UI] {
+			if _, err := fs.Stat(cfg.Assets, name); err != nil && Logger != nil {
+				Logger.Fatal("Docs asset %s is missing from DocsConfig.Assets (set DocsConfig.CDN to load it from %s)", name, docsCDNOrigin)
+			}
+		}
+	}
+	if cfg.CSP == "" {
+		cfg.CSP = docsCSP(cfg, serverURLs(c.cfg))
+	}
+
+	own, _ := fs.Sub(docsUIFiles, "docsui")
+	assetsPath := docsPath + "/assets/"
+	router.MuxRouter.HandleFunc(docsPath, c.serveDocs(cfg, specPath, docsPath)).Methods(http.MethodGet)
+	router.MuxRouter.PathPrefix(assetsPath).Handler(serveDocsAssets(assetsPath, own, cfg.Assets)).Methods(http.MethodGet)
+}
+
+func (c *openapiController) serveDocs(cfg DocsConfig, specPath, docsPath string) http.HandlerFunc {
+	title := c.cfg.Title
+	if title == "" {
+		title = "API"
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		base := strings.TrimSuffix(r.URL.Path, docsPath)
+		assets := r.URL.Path + "/assets/"
+
+		page := docsPage{
+			Title:   title,
+			SpecURL: base + specPath,
+			UI:      cfg.UI,
+			Theme:   cfg.Theme,
+			LogoURL: cfg.LogoURL,
+		}
+		for _, name := range docsVendorFiles[cfg.UI] {
+			src := assets + "vendor/" + name
+			if cfg.CDN {
+				src = docsCDNFiles[name]
+			}
+			if strings.HasSuffix(name, ".css") {
+				page.Styles = append(page.Styles, src)
+			} else {
+				page.Scripts = append(page.Scripts, src)
+			}
+		}
+		page.Styles = append(page.Styles, assets+"requiem-docs.css")
+		page.Scripts = append(page.Scripts, assets+"requiem-docs.js")
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", cfg.CSP)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		if err := docsPageTemplate.Execute(w, page); err != nil {
+			Logger.Error("Could not render docs page: %s", err)
+		}
+	}
+}
+
+// serveDocsAssets serves the page's own script and stylesheet, and the
+// renderer's files under vendor/.
+func serveDocsAssets(assetsPath string, own, vendor fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path[strings.Index(r.URL.Path, assetsPath)+len(assetsPath):]
+		fsys := own
+		if strings.HasPrefix(name, "vendor/") {
+			fsys, name = vendor, strings.TrimPrefix(name, "vendor/")
+		} else if name != "requiem-docs.js" && name != "requiem-docs.css" {
+			http.NotFound(w, r)
+			return
+		}
+		if !fs.ValidPath(name) {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+			w.Header().Set("Content-Type", ct)
+		}
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+	})
+}
+
+// docsCSP builds the docs page's policy: everything from the page's own origin
+// only, plus the CDN, the logo's origin and the servers "Try it out" calls.
+// Both renderers set inline styles, so style-src allows them; scripts never
+// run inline.
+func docsCSP(cfg DocsConfig, servers []string) string {
+	scripts := []string{"'self'"}
+	styles := []string{"'self'", "'unsafe-inline'"}
+	if cfg.CDN {
+		scripts = append(scripts, docsCDNOrigin)
+		styles = append(styles, docsCDNOrigin)
+	}
+	images := []string{"'self'", "data:"}
+	if o := urlOrigin(cfg.LogoURL); o != "" {
+		images = append(images, o)
+	}
+	connect := []string{"'self'"}
+	for _, s := range servers {
+		if o := urlOrigin(s); o != "" {
+			connect = append(connect, o)
+		}
+	}
+
+	return strings.Join([]string{
+		"default-src 'none'",
+		"script-src " + strings.Join(scripts, " "),
+		"style-src " + strings.Join(styles, " "),
+		"img-src " + strings.Join(images, " "),
+		"font-src 'self' data:",
+		"connect-src " + strings.Join(connect, " "),
+		"worker-src 'self' blob:",
+		"base-uri 'none'",
+		"form-action 'none'",
+		"frame-ancestors 'none'",
+	}, "; ")
+}
+
+// urlOrigin returns the scheme://host of an absolute URL, or "" for relative
+// ones, which the page's own origin already covers.
+func urlOrigin(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,104 @@
+package requiem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testDocsAssets = fstest.MapFS{
+	"swagger-ui.css":       {Data: []byte(".swagger-ui{}")},
+	"swagger-ui-bundle.js": {Data: []byte("var SwaggerUIBundle;")},
+	"redoc.standalone.js":  {Data: []byte("var Redoc;")},
+}
+
+func getDocs(r *Router, path string) *httptest.ResponseRecorder {
+	return serve(r, http.MethodGet, path, "", nil)
+}
+
+func TestDocs_SelfHosted(t *testing.T) {
+	r := specRouter(OpenAPIConfig{Title: "T", Version: "1", Docs: DocsConfig{Assets: testDocsAssets}}, DocController{})
+
+	rec := getDocs(r, "/api/docs")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	html := rec.Body.String()
+	assert.Contains(t, html, `href="/api/docs/assets/vendor/swagger-ui.css"`)
+	assert.Contains(t, html, `src="/api/docs/assets/vendor/swagger-ui-bundle.js"`)
+	assert.Contains(t, html, `src="/api/docs/assets/requiem-docs.js"`)
+	assert.Contains(t, html, `data-spec-url="/api/openapi.json"`)
+	assert.NotContains(t, html, "unpkg.com")
+	assert.NotContains(t, html, "<script>")
+
+	csp := rec.Header().Get("Content-Security-Policy")
+	assert.Contains(t, csp, "default-src 'none'")
+	assert.Contains(t, csp, "script-src 'self';")
+	assert.NotContains(t, csp, "unpkg.com")
+
+	rec = getDocs(r, "/api/docs/assets/vendor/swagger-ui-bundle.js")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "var SwaggerUIBundle;", rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+
+	rec = getDocs(r, "/api/docs/assets/requiem-docs.js")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "SwaggerUIBundle")
+
+	assert.Equal(t, http.StatusNotFound, getDocs(r, "/api/docs/assets/index.html").Code)
+	assert.Equal(t, http.StatusNotFound, getDocs(r, "/api/docs/assets/vendor/missing.js").Code)
+}
+
+func TestDocs_ReDocThemeAndLogo(t *testing.T) {
+	r := specRouter(OpenAPIConfig{Title: "T", Version: "1", Docs: DocsConfig{
+		UI:      DocsUIReDoc,
+		Assets:  testDocsAssets,
+		Theme:   DocsThemeDark,
+		LogoURL: "https://cdn.example.com/logo.png",
+	}}, DocController{})
+
+	rec := getDocs(r, "/api/docs")
+	html := rec.Body.String()
+	assert.Contains(t, html, `src="/api/docs/assets/vendor/redoc.standalone.js"`)
+	assert.NotContains(t, html, "swagger-ui")
+	assert.Contains(t, html, `data-ui="redoc"`)
+	assert.Contains(t, html, `class="requiem-docs-dark"`)
+	assert.Contains(t, html, `<img src="https://cdn.example.com/logo.png"`)
+	assert.Contains(t, rec.Header().Get("Content-Security-Policy"), "img-src 'self' data: https://cdn.example.com")
+}
+
+func TestDocs_CDN(t *testing.T) {
+	r := specRouter(OpenAPIConfig{Title: "T", Version: "1", Servers: []string{"https://api.example.com/v1"}, Docs: DocsConfig{CDN: true}}, DocController{})
+
+	rec := getDocs(r, "/api/docs")
+	assert.Contains(t, rec.Body.String(), "https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui-bundle.js")
+	csp := rec.Header().Get("Content-Security-Policy")
+	assert.Contains(t, csp, "script-src 'self' https://unpkg.com")
+	assert.Contains(t, csp, "connect-src 'self' https://api.example.com")
+}
+
+func TestDocs_EmbeddedSwaggerUI(t *testing.T) {
+	r := specRouter(OpenAPIConfig{Title: "T", Version: "1"}, DocController{})
+
+	html := getDocs(r, "/api/docs").Body.String()
+	assert.Contains(t, html, `src="/api/docs/assets/vendor/swagger-ui-bundle.js"`)
+	assert.NotContains(t, html, "unpkg.com")
+
+	rec := getDocs(r, "/api/docs/assets/vendor/swagger-ui-bundle.js")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `PACKAGE_VERSION:"5.17.14"`)
+	assert.Equal(t, http.StatusOK, getDocs(r, "/api/docs/assets/vendor/swagger-ui.css").Code)
+}
+
+func TestDocs_EmbeddedReDocFromCDN(t *testing.T) {
+	r := specRouter(OpenAPIConfig{Title: "T", Version: "1", Docs: DocsConfig{UI: DocsUIReDoc, CDN: true}}, DocController{})
+
+	html := getDocs(r, "/api/docs").Body.String()
+	assert.Contains(t, html, `src="https://unpkg.com/redoc@2.1.5/bundles/redoc.standalone.js"`)
+	assert.Contains(t, html, `src="/api/docs/assets/requiem-docs.js"`)
+
+	rec := getDocs(r, "/api/docs/assets/requiem-docs.js")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Redoc.init")
+}
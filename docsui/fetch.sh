@@ -0,0 +1,22 @@
+#!/bin/sh
+# Downloads the pinned Swagger UI and ReDoc bundles into docsui/vendor, where
+# they are committed and embedded into the binary so the docs page needs no
+# CDN. Run through `go generate` from the module root when bumping the pins
+# (keep docsCDNFiles in docs.go in step), then commit the files.
+set -eu
+
+SWAGGER_UI_VERSION=5.17.14
+REDOC_VERSION=2.1.5
+
+dir="$(dirname "$0")/vendor"
+mkdir -p "$dir"
+
+fetch() {
+  curl -fsSL "$1" -o "$dir/$2"
+}
+
+fetch "https://unpkg.com/swagger-ui-dist@$SWAGGER_UI_VERSION/swagger-ui.css" swagger-ui.css
+fetch "https://unpkg.com/swagger-ui-dist@$SWAGGER_UI_VERSION/swagger-ui-bundle.js" swagger-ui-bundle.js
+fetch "https://unpkg.com/swagger-ui-dist@$SWAGGER_UI_VERSION/LICENSE" swagger-ui.LICENSE
+fetch "https://unpkg.com/redoc@$REDOC_VERSION/bundles/redoc.standalone.js" redoc.standalone.js
+fetch "https://unpkg.com/redoc@$REDOC_VERSION/LICENSE" redoc.LICENSE
@@ -0,0 +1,44 @@
+// Starts the renderer selected on #requiem-docs. It lives outside the page so
+// the docs work under a Content-Security-Policy without 'unsafe-inline'.
+(function () {
+  "use strict";
+
+  var el = document.getElementById("requiem-docs");
+  var opts = el.dataset;
+  var dark = opts.theme === "dark";
+
+  function unavailable(name) {
+    el.className = "requiem-docs-error";
+    el.textContent = name + " could not be loaded. Check that its assets are served next to this page.";
+  }
+
+  if (opts.ui === "redoc") {
+    if (typeof Redoc === "undefined") {
+      unavailable("ReDoc");
+      return;
+    }
+    var theme = {};
+    if (dark) {
+      theme = {
+        colors: { text: { primary: "#e6e6e6", secondary: "#b3b3b3" } },
+        sidebar: { backgroundColor: "#1e1e1e", textColor: "#e6e6e6" },
+        rightPanel: { backgroundColor: "#121212" },
+        typography: { links: { color: "#7cb7ff" } }
+      };
+    }
+    Redoc.init(opts.specUrl, { theme: theme }, el);
+    return;
+  }
+
+  if (typeof SwaggerUIBundle === "undefined") {
+    unavailable("Swagger UI");
+    return;
+  }
+  window.ui = SwaggerUIBundle({
+    url: opts.specUrl,
+    domNode: el,
+    deepLinking: true,
+    validatorUrl: null,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIBundle.SwaggerUIStandalonePreset]
+  });
+})();
@@ -0,0 +1,117 @@
+package requiem
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// OpenAPIExamplesProvider lets a request or response type supply named example
+// payloads. They are emitted as media type examples wherever the type is used
+// as a whole body, alongside any added with Route.Example or Route.BodyExample.
+type OpenAPIExamplesProvider interface {
+	Examples() map[string]interface{}
+}
+
+var openAPIExamplesProviderType = reflect.TypeOf((*OpenAPIExamplesProvider)(nil)).Elem()
+
+// Example adds an example payload for the response with the given status.
+func (rt *Route) Example(status int, v interface{}) *Route {
+	if rt.examples == nil {
+		rt.examples = make(map[int][]interface{})
+	}
+	rt.examples[status] = append(rt.examples[status], v)
+	return rt
+}
+
+// BodyExample adds an example request body. It is also shown on the route's
+// MCP tool.
+func (rt *Route) BodyExample(v interface{}) *Route {
+	rt.bodyExamples = append(rt.bodyExamples, v)
+	return rt
+}
+
+// typeExamples returns the examples declared by t's Examples method, if any.
+func typeExamples(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	if t.Implements(openAPIExamplesProviderType) {
+		return reflect.New(t).Elem().Interface().(OpenAPIExamplesProvider).Examples()
+	}
+	if reflect.PointerTo(t).Implements(openAPIExamplesProviderType) {
+		return reflect.New(t).Interface().(OpenAPIExamplesProvider).Examples()
+	}
+	return nil
+}
+
+// mediaExamples merges type-level and route-level examples into an OpenAPI
+// examples map ({name: {value: ...}}), or nil when there are none. Route-level
+// examples are named example1, example2, ...
+func mediaExamples(t reflect.Type, values []interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for name, v := range typeExamples(t) {
+		out[name] = map[string]interface{}{"value": v}
+	}
+	for i, v := range values {
+		out[fmt.Sprintf("example%d", i+1)] = map[string]interface{}{"value": v}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// exampleValues flattens mediaExamples output to its values, ordered by name,
+// for JSON Schema's examples keyword.
+func exampleValues(examples map[string]interface{}) []interface{} {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		out = append(out, examples[name].(map[string]interface{})["value"])
+	}
+	return out
+}
+
+// applyExampleTag sets a property's example from its `example:"..."` tag,
+// converting it to the schema's type so it renders as e.g. 3 rather than "3".
+// Arrays and objects are written as JSON.
+func applyExampleTag(schema map[string]interface{}, tag string) {
+	if tag == "" {
+		return
+	}
+	typ, _ := schema["type"].(string)
+	var v interface{} = tag
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(tag, 10, 64); err == nil {
+			v = n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(tag, 64); err == nil {
+			v = f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(tag); err == nil {
+			v = b
+		}
+	case "array", "object", "":
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(tag), &decoded); err == nil {
+			v = decoded
+		}
+	}
+	schema["example"] = v
+}
@@ -0,0 +1,63 @@
+package requiem
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Pet struct {
+	Name string   `json:"name" example:"Rex"`
+	Age  int      `json:"age" example:"3"`
+	Good bool     `json:"good" example:"true"`
+	Tags []string `json:"tags" example:"[\"dog\",\"brown\"]"`
+}
+
+type NewPet struct {
+	Name string `json:"name"`
+}
+
+func (NewPet) Examples() map[string]interface{} {
+	return map[string]interface{}{"minimal": NewPet{Name: "Rex"}}
+}
+
+type petController struct{}
+
+func (petController) Load(router *Router) {
+	r := router.NewRestRouter("/pets")
+	r.Post("/", func(ctx HTTPContext) {}, NewPet{}).
+		BodyExample(NewPet{Name: "Fido"}).
+		Returns(201, Pet{}, "Created").
+		Example(201, Pet{Name: "Fido", Age: 1})
+}
+
+func TestExamples_InSpec(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{petController{}})
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buildDoc(OpenAPIConfig{Title: "T", Version: "1"}, router.routes), &spec))
+
+	props := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})["Pet"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, "Rex", props["name"].(map[string]interface{})["example"])
+	assert.EqualValues(t, 3, props["age"].(map[string]interface{})["example"])
+	assert.Equal(t, true, props["good"].(map[string]interface{})["example"])
+	assert.Equal(t, []interface{}{"dog", "brown"}, props["tags"].(map[string]interface{})["example"])
+
+	op := spec["paths"].(map[string]interface{})["/pets/"].(map[string]interface{})["post"].(map[string]interface{})
+	reqExamples := op["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["examples"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"value": map[string]interface{}{"name": "Rex"}}, reqExamples["minimal"])
+	assert.Equal(t, map[string]interface{}{"value": map[string]interface{}{"name": "Fido"}}, reqExamples["example1"])
+
+	resp := op["responses"].(map[string]interface{})["201"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	example := resp["examples"].(map[string]interface{})["example1"].(map[string]interface{})["value"].(map[string]interface{})
+	assert.Equal(t, "Fido", example["name"])
+}
+
+func TestExamples_InMCPInputSchema(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{petController{}})
+	schema := buildInputSchema(router.routes[0])
+
+	body := schema["properties"].(map[string]interface{})["body"].(map[string]interface{})
+	assert.Equal(t, []interface{}{NewPet{Name: "Fido"}, NewPet{Name: "Rex"}}, body["examples"])
+	assert.Equal(t, "#/$defs/NewPet", body["allOf"].([]interface{})[0].(map[string]interface{})["$ref"])
+}
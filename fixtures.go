@@ -0,0 +1,286 @@
+package requiem
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// fixtureRefPrefix marks a fixture value that refers to another fixture row:
+// "$ref:alice" resolves to alice's primary key, "$ref:alice.Email" to a field.
+const fixtureRefPrefix = "$ref:"
+
+// fixtureNameKey optionally names a row in list-style fixture files so other
+// rows can reference it.
+const fixtureNameKey = "_name"
+
+type fixtureRow struct {
+	file   string
+	model  reflect.Type
+	name   string
+	values map[string]interface{}
+}
+
+// LoadFixtures inserts the rows described by every .yml, .yaml and .json file
+// in fsys (walked recursively, in lexical order) into db. Each file maps a
+// model name (its Go type name or table name) to rows, either as a list or as
+// a map of row names to rows:
+//
+//	User:
+//	  alice: {email: alice@example.com}
+//	Widget:
+//	  - name: Red widget
+//	    owner_id: $ref:alice
+//
+// Keys are column names or Go field names. A "$ref:name" value resolves to the
+// primary key of the named row and "$ref:name.Field" to one of its fields, so
+// rows may reference each other across files regardless of order. Row names
+// are shared by all models and files, so each may only be used once. Rows are
+// inserted in one transaction, and a failed load inserts none of them. Tables
+// are expected to exist already (see Server.AutoMigrate).
+func LoadFixtures(db *gorm.DB, fsys fs.FS, models ...interface{}) error {
+	types := map[string]reflect.Type{}
+	for _, m := range models {
+		t := reflect.TypeOf(m)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		types[t.Name()] = t
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return err
+		}
+		types[stmt.Schema.Table] = t
+	}
+
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch path.Ext(p) {
+		case ".yml", ".yaml", ".json":
+			if !d.IsDir() {
+				files = append(files, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	var rows []*fixtureRow
+	names := map[string]*fixtureRow{}
+	for _, f := range files {
+		data, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return err
+		}
+		fileRows, err := parseFixtureFile(f, data, types)
+		if err != nil {
+			return err
+		}
+		for _, row := range fileRows {
+			if row.name == "" {
+				continue
+			}
+			if prev, ok := names[row.name]; ok {
+				return fmt.Errorf("fixtures: %s: row name %q is already used by %s in %s", row.file, row.name, prev.model.Name(), prev.file)
+			}
+			names[row.name] = row
+		}
+		rows = append(rows, fileRows...)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return insertFixtureRows(tx, rows)
+	})
+}
+
+// parseFixtureFile decodes one fixture file, preserving the order of its
+// models and rows. JSON files are parsed as YAML, of which JSON is a subset.
+func parseFixtureFile(file string, data []byte, types map[string]reflect.Type) ([]*fixtureRow, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("fixtures: %s: %w", file, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("fixtures: %s: top level must map model names to rows", file)
+	}
+
+	var rows []*fixtureRow
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		modelName, body := root.Content[i].Value, root.Content[i+1]
+		t, ok := types[modelName]
+		if !ok {
+			return nil, fmt.Errorf("fixtures: %s: unknown model %q", file, modelName)
+		}
+
+		add := func(name string, n *yaml.Node) error {
+			values := map[string]interface{}{}
+			if err := n.Decode(&values); err != nil {
+				return fmt.Errorf("fixtures: %s: %s: %w", file, modelName, err)
+			}
+			if name == "" {
+				name, _ = values[fixtureNameKey].(string)
+			}
+			delete(values, fixtureNameKey)
+			rows = append(rows, &fixtureRow{file: file, model: t, name: name, values: values})
+			return nil
+		}
+
+		switch body.Kind {
+		case yaml.SequenceNode:
+			for _, n := range body.Content {
+				if err := add("", n); err != nil {
+					return nil, err
+				}
+			}
+		case yaml.MappingNode:
+			for j := 0; j+1 < len(body.Content); j += 2 {
+				if err := add(body.Content[j].Value, body.Content[j+1]); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("fixtures: %s: %s must be a list or map of rows", file, modelName)
+		}
+	}
+	return rows, nil
+}
+
+// insertFixtureRows inserts rows whose references are all resolvable, repeating
+// until every row is in. Rows stuck on a missing or circular reference fail the
+// load.
+func insertFixtureRows(db *gorm.DB, rows []*fixtureRow) error {
+	type inserted struct {
+		schema *schema.Schema
+		value  reflect.Value
+	}
+	named := map[string]inserted{}
+
+	resolve := func(v interface{}) (interface{}, bool, error) {
+		s, ok := v.(string)
+		if !ok || !strings.HasPrefix(s, fixtureRefPrefix) {
+			return v, true, nil
+		}
+		ref := strings.TrimPrefix(s, fixtureRefPrefix)
+		name, fieldName := ref, ""
+		if i := strings.Index(ref, "."); i >= 0 {
+			name, fieldName = ref[:i], ref[i+1:]
+		}
+		target, ok := named[name]
+		if !ok {
+			return nil, false, nil
+		}
+		field := target.schema.PrioritizedPrimaryField
+		if fieldName != "" {
+			field = target.schema.LookUpField(fieldName)
+		}
+		if field == nil {
+			return nil, false, fmt.Errorf("fixtures: cannot resolve %q", s)
+		}
+		val, _ := field.ValueOf(context.Background(), target.value)
+		return val, true, nil
+	}
+
+	pending := rows
+	for len(pending) > 0 {
+		var next []*fixtureRow
+		for _, row := range pending {
+			values := make(map[string]interface{}, len(row.values))
+			ready := true
+			for k, v := range row.values {
+				resolved, ok, err := resolve(v)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					ready = false
+					break
+				}
+				values[k] = resolved
+			}
+			if !ready {
+				next = append(next, row)
+				continue
+			}
+
+			ptr := reflect.New(row.model)
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(ptr.Interface()); err != nil {
+				return err
+			}
+			for k, v := range values {
+				field := stmt.Schema.LookUpField(k)
+				if field == nil {
+					return fmt.Errorf("fixtures: %s: %s has no field %q", row.file, row.model.Name(), k)
+				}
+				if err := field.Set(context.Background(), ptr.Elem(), v); err != nil {
+					return fmt.Errorf("fixtures: %s: %s.%s: %w", row.file, row.model.Name(), k, err)
+				}
+			}
+			if err := db.Create(ptr.Interface()).Error; err != nil {
+				return fmt.Errorf("fixtures: %s: %s: %w", row.file, row.model.Name(), err)
+			}
+			if row.name != "" {
+				named[row.name] = inserted{schema: stmt.Schema, value: ptr.Elem()}
+			}
+		}
+		if len(next) == len(pending) {
+			names := make([]string, 0, len(next))
+			for _, row := range next {
+				names = append(names, fmt.Sprintf("%s in %s", row.model.Name(), row.file))
+			}
+			return fmt.Errorf("fixtures: unresolvable references in %s", strings.Join(names, ", "))
+		}
+		pending = next
+	}
+	return nil
+}
+
+// ResetDB drops the tables of the given models and migrates them again, leaving
+// the database empty. It exists mainly for tests sharing UseInMemoryDB's
+// database ("file::memory:?cache=shared"), which otherwise keeps rows across
+// test cases for as long as any connection is open.
+func ResetDB(db *gorm.DB, models ...interface{}) error {
+	if err := db.Migrator().DropTable(models...); err != nil {
+		return err
+	}
+	return db.AutoMigrate(models...)
+}
+
+// UseFixtures loads fixtures from fsys into the server's database when Start is
+// called, after the server's AutoMigrate calls. It is intended for local
+// development; guard it behind your own environment check.
+func (s *Server) UseFixtures(fsys fs.FS, models ...interface{}) {
+	s.fixtures = append(s.fixtures, func() error {
+		return LoadFixtures(s.db, fsys, models...)
+	})
+}
+
+// LoadFixtures loads fixtures from fsys into the server's database immediately,
+// e.g. at the start of a test case.
+func (s *Server) LoadFixtures(fsys fs.FS, models ...interface{}) error {
+	return LoadFixtures(s.db, fsys, models...)
+}
+
+// ResetDB empties the server's database by dropping and re-migrating every
+// model passed to AutoMigrate.
+func (s *Server) ResetDB() error {
+	return ResetDB(s.db, s.models...)
+}
@@ -0,0 +1,99 @@
+package requiem
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type fixtureUser struct {
+	ID    uint `gorm:"primaryKey"`
+	Email string
+}
+
+type fixtureWidget struct {
+	ID         uint `gorm:"primaryKey"`
+	Name       string
+	OwnerID    uint
+	OwnerEmail string
+}
+
+func fixtureDB(t *testing.T) *gorm.DB {
+	return testDB(t, "", &fixtureUser{}, &fixtureWidget{})
+}
+
+func TestFixtures_ResolvesReferencesAcrossFiles(t *testing.T) {
+	db := fixtureDB(t)
+	fsys := fstest.MapFS{
+		// Sorted before users.yml, so the references are resolved out of order.
+		"a_widgets.json": {Data: []byte(`{"fixtureWidget": [
+			{"name": "Red", "owner_id": "$ref:bob", "owner_email": "$ref:bob.Email"}
+		]}`)},
+		"users.yml": {Data: []byte(`
+fixture_users:
+  alice: {email: alice@example.com}
+  bob: {Email: bob@example.com}
+`)},
+		"README.md": {Data: []byte("ignored")},
+	}
+
+	assert.NoError(t, LoadFixtures(db, fsys, fixtureUser{}, &fixtureWidget{}))
+
+	var bob fixtureUser
+	assert.NoError(t, db.Where("email = ?", "bob@example.com").First(&bob).Error)
+
+	var widgets []fixtureWidget
+	db.Find(&widgets)
+	assert.Len(t, widgets, 1)
+	assert.Equal(t, "Red", widgets[0].Name)
+	assert.Equal(t, bob.ID, widgets[0].OwnerID)
+	assert.Equal(t, "bob@example.com", widgets[0].OwnerEmail)
+}
+
+func TestFixtures_Errors(t *testing.T) {
+	db := fixtureDB(t)
+
+	err := LoadFixtures(db, fstest.MapFS{"x.yml": {Data: []byte("Nope: []")}}, fixtureUser{})
+	assert.ErrorContains(t, err, `unknown model "Nope"`)
+
+	err = LoadFixtures(db, fstest.MapFS{"x.yml": {Data: []byte("fixtureUser: [{nickname: x}]")}}, fixtureUser{})
+	assert.ErrorContains(t, err, `no field "nickname"`)
+
+	err = LoadFixtures(db, fstest.MapFS{"x.yml": {Data: []byte("fixtureWidget: [{owner_id: $ref:ghost}]")}}, fixtureWidget{})
+	assert.ErrorContains(t, err, "unresolvable references")
+
+	err = LoadFixtures(db, fstest.MapFS{
+		"a.yml": {Data: []byte("fixtureUser: {alice: {email: a@example.com}}")},
+		"b.yml": {Data: []byte("fixtureWidget: {alice: {name: Blue}}")},
+	}, fixtureUser{}, fixtureWidget{})
+	assert.EqualError(t, err, `fixtures: b.yml: row name "alice" is already used by fixtureUser in a.yml`)
+}
+
+func TestFixtures_FailedLoadInsertsNothing(t *testing.T) {
+	db := fixtureDB(t)
+
+	err := LoadFixtures(db, fstest.MapFS{"x.yml": {Data: []byte(`
+fixtureUser:
+  - email: alice@example.com
+fixtureWidget:
+  - owner_id: $ref:ghost
+`)}}, fixtureUser{}, fixtureWidget{})
+	assert.ErrorContains(t, err, "unresolvable references")
+
+	var count int64
+	db.Model(&fixtureUser{}).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestResetDB_EmptiesTables(t *testing.T) {
+	db := fixtureDB(t)
+	db.Create(&fixtureUser{Email: "leftover@example.com"})
+
+	assert.NoError(t, ResetDB(db, &fixtureUser{}, &fixtureWidget{}))
+
+	var count int64
+	db.Model(&fixtureUser{}).Count(&count)
+	assert.Zero(t, count)
+}
@@ -1,5 +1,7 @@
 package requiem
 
+import "net/http"
+
 type HealthcheckController struct {
 }
 
@@ -7,7 +9,51 @@ func (c HealthcheckController) healthcheck(ctx HTTPContext) {
 	ctx.SendStatus(200)
 }
 
+// readiness reports whether the server can take traffic: the primary DB must
+// answer a ping. Unavailable read replicas only mark the server degraded,
+// since reads fall back to the primary. Replica results also refresh the
+// health used for read routing.
+func (c HealthcheckController) readiness(ctx HTTPContext) {
+	status := map[string]interface{}{"status": "ok"}
+	ready := true
+
+	db := ctx.router.DB
+	if db != nil {
+		if err := pingDB(db); err != nil {
+			status["primary"] = err.Error()
+			ready = false
+		} else {
+			status["primary"] = "ok"
+		}
+
+		if rr := replicaRouterOf(db); rr != nil {
+			replicas := map[string]string{}
+			healthy := 0
+			for name, ok := range rr.checkHealth() {
+				if ok {
+					replicas[name] = "ok"
+					healthy++
+				} else {
+					replicas[name] = "unavailable"
+				}
+			}
+			status["replicas"] = replicas
+			if healthy < len(replicas) {
+				status["status"] = "degraded"
+			}
+		}
+	}
+
+	if !ready {
+		status["status"] = "unavailable"
+		ctx.SendJSONWithStatus(status, http.StatusServiceUnavailable)
+		return
+	}
+	ctx.SendJSON(status)
+}
+
 func (c HealthcheckController) Load(router *Router) {
 	r := router.NewRestRouter("/healthcheck")
 	r.Get("", c.healthcheck).ExcludeFromSpec()
+	r.Get("/ready", c.readiness).ExcludeFromSpec()
 }
@@ -0,0 +1,40 @@
+package requiem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testDB opens an in-memory SQLite database private to the test and
+// migrates models into it. The cache is shared so every pooled connection
+// sees the same database; name tells several databases in one test apart.
+func testDB(t *testing.T, name string, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+name+"?mode=memory&cache=shared"), buildGormConfig(false))
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(models...))
+	return db
+}
+
+// serve sends a request through the router the way http.Server does and
+// returns the recorded response.
+func serve(r *Router, method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return serveRequest(r, req)
+}
+
+// serveRequest is serve for a request built by the caller.
+func serveRequest(r *Router, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
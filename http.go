@@ -2,11 +2,14 @@ package requiem
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 // HTTPContext provides utility functions for HTTP requests/responses
@@ -15,6 +18,15 @@ type HTTPContext struct {
 	Request    *http.Request
 	Body       interface{}
 	attributes map[string]interface{}
+	router     *Router
+	route      *Route
+	dbRouting  *dbRouting
+}
+
+// newHTTPContext builds the context handed to interceptors and handlers for a
+// request dispatched through the given router.
+func newHTTPContext(w http.ResponseWriter, r *http.Request, body interface{}, router *Router, rt *Route) HTTPContext {
+	return HTTPContext{Request: r, Response: w, Body: body, attributes: make(map[string]interface{}), router: router, route: rt, dbRouting: newDBRouting(r, rt)}
 }
 
 // SendJSON converts the given interface into JSON and writes to the response.
@@ -37,11 +49,74 @@ func (ctx *HTTPContext) GetParam(p string) string {
 	return mux.Vars(ctx.Request)[p]
 }
 
+// ParamError is returned by the typed param accessors. Its issue has the
+// same reason request validation reports for the value.
+type ParamError struct {
+	ValidationIssue
+}
+
+func (e *ParamError) Error() string {
+	return e.In + " param " + e.Name + " " + e.Reason
+}
+
+// GetParamInt parses the given path parameter as a base-10 int64. Routes
+// constrained to digits, e.g. {id:[0-9]+}, only fail here for values that
+// overflow.
+func (ctx *HTTPContext) GetParamInt(p string) (int64, error) {
+	var n int64
+	err := ctx.typedParam(p, "integer", func(v string) (err error) {
+		n, err = strconv.ParseInt(v, 10, 64)
+		return err
+	})
+	return n, err
+}
+
+// GetParamFloat parses the given path parameter as a float64.
+func (ctx *HTTPContext) GetParamFloat(p string) (float64, error) {
+	var f float64
+	err := ctx.typedParam(p, "number", func(v string) (err error) {
+		f, err = strconv.ParseFloat(v, 64)
+		return err
+	})
+	return f, err
+}
+
+// GetParamBool parses the given path parameter as a bool.
+func (ctx *HTTPContext) GetParamBool(p string) (bool, error) {
+	var b bool
+	err := ctx.typedParam(p, "boolean", func(v string) (err error) {
+		b, err = strconv.ParseBool(v)
+		return err
+	})
+	return b, err
+}
+
+// typedParam parses a path parameter as typ. Every failure is a *ParamError.
+func (ctx *HTTPContext) typedParam(p, typ string, parse func(string) error) error {
+	v, ok := mux.Vars(ctx.Request)[p]
+	if !ok {
+		return &ParamError{ValidationIssue{In: "path", Name: p, Reason: "is required"}}
+	}
+	if err := parse(v); err != nil {
+		return &ParamError{ValidationIssue{In: "path", Name: p, Reason: typeMismatch(typ, v)}}
+	}
+	return nil
+}
+
 // GetQueryParam obtains the given query parameter from the request URL.
 func (ctx *HTTPContext) GetQueryParam(p string) string {
 	return ctx.Request.URL.Query().Get(p)
 }
 
+// GetCookie returns the value of the named request cookie, or "" if absent.
+func (ctx *HTTPContext) GetCookie(name string) string {
+	c, err := ctx.Request.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
 // GetAttribute returns the context-scoped value for the given key
 func (ctx *HTTPContext) GetAttribute(key string) interface{} {
 	return ctx.attributes[key]
@@ -52,14 +127,74 @@ func (ctx *HTTPContext) SetAttribute(key string, attr interface{}) {
 	ctx.attributes[key] = attr
 }
 
-// ReadJSON decodes the provided stream into the given interface.
+// DB returns the router's database handle for this request. When tenancy is
+// enabled (Server.UseTenancy) the handle is scoped to the request's tenant, and
+// every query on it fails with ErrNoTenant if no tenant could be resolved.
+// When read replicas are configured, reads on GET and ReadOnly routes go to a
+// replica until the request performs its first write.
+func (ctx *HTTPContext) DB() *gorm.DB {
+	if ctx.router == nil || ctx.router.DB == nil {
+		return nil
+	}
+	db := ctx.router.DB
+	if replicaRouterOf(db) != nil && ctx.dbRouting != nil {
+		db = db.Set(dbRoutingKey, ctx.dbRouting)
+	}
+	if cfg := ctx.router.tenancy; cfg != nil {
+		db = db.Scopes(cfg.Scope(ctx.Tenant()))
+	}
+	return db
+}
+
+// ReadJSON decodes the provided stream into the given interface. Interface
+// fields registered with RegisterUnion are decoded into their concrete types.
 func ReadJSON(r io.Reader, v interface{}) interface{} {
-	t := reflect.TypeOf(v)
-	o := reflect.New(t).Interface()
-	json.NewDecoder(r).Decode(o)
+	o, _ := readJSONBody(r, v)
 	return o
 }
 
+// readJSONBody is ReadJSON, also returning an error when a union's
+// discriminator is missing or unknown. Malformed or mistyped JSON is
+// returned as a jsonDecodeError, which is only reported when requests are
+// validated. An empty body isn't an error.
+func readJSONBody(r io.Reader, v interface{}) (interface{}, error) {
+	t := reflect.TypeOf(v)
+	o := reflect.New(t)
+	if !containsUnion(t, map[reflect.Type]bool{}) {
+		return o.Interface(), decodeError(json.NewDecoder(r).Decode(o.Interface()))
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return o.Interface(), decodeError(err)
+	}
+	// Union fields can't be unmarshaled into directly; decodeUnions fills them.
+	err := json.Unmarshal(raw, o.Interface())
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Type.Kind() == reflect.Interface {
+		err = nil
+	}
+	if uerr := decodeUnions(raw, o.Elem()); uerr != nil {
+		return o.Interface(), uerr
+	}
+	return o.Interface(), decodeError(err)
+}
+
+// jsonDecodeError is a request body that isn't valid JSON or doesn't fit the
+// body type. Without request validation it is tolerated, as ReadJSON always
+// has, and the body is left to its validate tags.
+type jsonDecodeError struct{ err error }
+
+func (e jsonDecodeError) Error() string { return e.err.Error() }
+
+func (e jsonDecodeError) Unwrap() error { return e.err }
+
+func decodeError(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+	return jsonDecodeError{err}
+}
+
 // SendJSON converts the given interface into JSON and writes to the provided stream.
 func SendJSON(w http.ResponseWriter, v interface{}) {
 	w.Header().Add("Content-Type", "application/json")
@@ -0,0 +1,182 @@
+// Package specdoc holds the helpers the spec tooling packages share for
+// working with decoded OpenAPI documents.
+package specdoc
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Obj is a decoded JSON/YAML object.
+type Obj = map[string]interface{}
+
+// ErrNotOpenAPI is returned by Parse for documents without an "openapi" field.
+var ErrNotOpenAPI = errors.New("not an OpenAPI document")
+
+// Parse decodes an OpenAPI document. YAML is a superset of JSON, so one
+// decoder covers both.
+func Parse(data []byte) (Obj, error) {
+	var doc Obj
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("empty document")
+	}
+	doc = Normalize(doc).(Obj)
+	if _, ok := doc["openapi"]; !ok {
+		return nil, ErrNotOpenAPI
+	}
+	return doc, nil
+}
+
+// Normalize converts the maps YAML decodes for non-string keys, such as
+// unquoted status codes, to string-keyed ones.
+func Normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case Obj:
+		for k, e := range t {
+			t[k] = Normalize(e)
+		}
+		return t
+	case map[interface{}]interface{}:
+		out := Obj{}
+		for k, e := range t {
+			out[fmt.Sprint(k)] = Normalize(e)
+		}
+		return out
+	case []interface{}:
+		for i, e := range t {
+			t[i] = Normalize(e)
+		}
+	}
+	return v
+}
+
+// SchemaType returns the schema's type, ignoring "null" in 3.1 type arrays.
+// Several remaining types are sorted and joined with "|".
+func SchemaType(s Obj) string {
+	switch t := s["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		var types []string
+		for _, v := range t {
+			if v != "null" {
+				types = append(types, fmt.Sprint(v))
+			}
+		}
+		sort.Strings(types)
+		return strings.Join(types, "|")
+	}
+	return ""
+}
+
+// Nullable reports whether the schema allows null, in either the 3.0 or the
+// 3.1 form.
+func Nullable(s Obj) bool {
+	if Truthy(s["nullable"]) {
+		return true
+	}
+	for _, v := range List(s["type"]) {
+		if v == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// Object returns v as an object, or nil.
+func Object(v interface{}) Obj {
+	o, _ := v.(Obj)
+	return o
+}
+
+// List returns v as a list, or nil.
+func List(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+// Str returns v as a string, or "".
+func Str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// Truthy reports whether v is the boolean true.
+func Truthy(v interface{}) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+// SortedKeys returns the keys of m in order.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var initialisms = map[string]bool{
+	"api": true, "db": true, "html": true, "http": true, "https": true, "id": true, "ip": true,
+	"json": true, "sql": true, "uri": true, "url": true, "uuid": true, "xml": true,
+}
+
+// ExportName converts an identifier such as "get_widgets_id" or "getWidget"
+// to an exported Go name: GetWidgetsID, GetWidget.
+func ExportName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	name := b.String()
+	if name == "" {
+		return "X"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// LowerName converts an identifier to an unexported Go name that isn't a
+// keyword: "id" -> "id", "widget_id" -> "widgetID", "type" -> "type_".
+func LowerName(s string) string {
+	r := []rune(ExportName(s))
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	switch {
+	case n == len(r):
+		n = len(r)
+	case n > 1:
+		n--
+	}
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	name := string(r)
+	if token.IsKeyword(name) {
+		name += "_"
+	}
+	return name
+}
@@ -0,0 +1,34 @@
+package specdoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportName(t *testing.T) {
+	assert.Equal(t, "GetWidgetsID", ExportName("get_widgets_id"))
+	assert.Equal(t, "GetWidget", ExportName("getWidget"))
+	assert.Equal(t, "X2fa", ExportName("2fa"))
+	assert.Equal(t, "widgetID", LowerName("widget_id"))
+	assert.Equal(t, "id", LowerName("id"))
+	assert.Equal(t, "type_", LowerName("type"))
+}
+
+func TestSchemaType(t *testing.T) {
+	assert.Equal(t, "string", SchemaType(Obj{"type": "string"}))
+	assert.Equal(t, "integer|string", SchemaType(Obj{"type": []interface{}{"string", "null", "integer"}}))
+	assert.True(t, Nullable(Obj{"type": []interface{}{"string", "null"}}))
+	assert.True(t, Nullable(Obj{"type": "string", "nullable": true}))
+	assert.False(t, Nullable(Obj{"type": "string"}))
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte("openapi: 3.0.3\npaths:\n  /a:\n    get:\n      responses:\n        200:\n          description: ok\n"))
+	assert.NoError(t, err)
+	responses := Object(Object(Object(Object(doc["paths"])["/a"])["get"])["responses"])
+	assert.Equal(t, []string{"200"}, SortedKeys(responses))
+
+	_, err = Parse([]byte(`{"swagger": "2.0"}`))
+	assert.ErrorIs(t, err, ErrNotOpenAPI)
+}
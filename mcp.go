@@ -74,7 +74,8 @@ func (rt *Route) IncludeInMCP() *Route {
 	return rt
 }
 
-// MCPTool overrides the auto-generated MCP tool name for this route.
+// MCPTool overrides the MCP tool name for this route, which otherwise defaults
+// to the route's operationId.
 func (rt *Route) MCPTool(name string) *Route {
 	rt.mcpToolName = name
 	return rt
@@ -105,9 +106,13 @@ type mcpController struct {
 	cfg    MCPConfig
 	router *Router
 
-	once  sync.Once
-	tools []mcpTool
-	index map[string]*mcpTool
+	// mu guards the tool cache, which is rebuilt whenever the router's
+	// generation moves past the one it was built from.
+	mu         sync.Mutex
+	built      bool
+	generation uint64
+	tools      []mcpTool
+	index      map[string]*mcpTool
 }
 
 func (c *mcpController) Load(router *Router) {
@@ -212,7 +217,7 @@ func (c *mcpController) dispatch(ctx HTTPContext, req rpcRequest) (interface{},
 	case "tools/list":
 		return map[string]interface{}{"tools": c.toolList(ctx)}, nil
 	case "tools/call":
-		return c.invoke(ctx.Request, req.Params)
+		return c.invoke(ctx, req.Params)
 	default:
 		return nil, &rpcError{Code: -32601, Message: fmt.Sprintf("Method not found: %s", req.Method)}
 	}
@@ -229,11 +234,7 @@ func mcpRequiresAuth(method string) bool {
 // response is a throwaway recorder so an Authenticate hook that writes a status on
 // failure (e.g. 401) doesn't corrupt the real JSON-RPC response.
 func (c *mcpController) newContext(r *http.Request) HTTPContext {
-	return HTTPContext{
-		Request:    r,
-		Response:   &responseRecorder{header: http.Header{}, status: http.StatusOK},
-		attributes: map[string]interface{}{},
-	}
+	return newHTTPContext(&responseRecorder{header: http.Header{}, status: http.StatusOK}, r, nil, c.router, nil)
 }
 
 func (c *mcpController) success(id json.RawMessage, result interface{}) rpcResponse {
@@ -276,38 +277,50 @@ func (c *mcpController) initializeResult(params json.RawMessage) map[string]inte
 
 // --- Tool generation ---
 
-func (c *mcpController) build() {
-	c.once.Do(func() {
-		c.index = map[string]*mcpTool{}
-		used := map[string]bool{}
-		for _, rt := range c.router.routes {
-			if rt.mcpExcluded {
-				continue
-			}
-			// In allowlist mode, a route must opt in explicitly. Exclude still wins.
-			if c.cfg.OptIn && !rt.mcpIncluded {
-				continue
-			}
-			name := rt.mcpToolName
-			if name == "" {
-				name = defaultToolName(rt)
-			}
-			// Dedup both auto-generated and overridden names so a duplicate
-			// MCPTool override can't silently shadow another tool.
-			name = uniqueToolName(name, used)
-			used[name] = true
-			c.tools = append(c.tools, mcpTool{
-				name:        name,
-				description: toolDescription(rt),
-				inputSchema: buildInputSchema(rt),
-				authorizer:  rt.authorizer,
-				route:       rt,
-			})
-		}
-		for i := range c.tools {
-			c.index[c.tools[i].name] = &c.tools[i]
+// build returns the tools, first rebuilding them if routes were registered or
+// Router.InvalidateSpec was called since they were last built.
+func (c *mcpController) build() ([]mcpTool, map[string]*mcpTool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.router.mu.RLock()
+	defer c.router.mu.RUnlock()
+	if c.built && c.generation == c.router.generation {
+		return c.tools, c.index
+	}
+	c.built, c.generation = true, c.router.generation
+
+	var tools []mcpTool
+	index := map[string]*mcpTool{}
+	used := map[string]bool{}
+	for _, rt := range c.router.routes {
+		if rt.mcpExcluded {
+			continue
 		}
-	})
+		// In allowlist mode, a route must opt in explicitly. Exclude still wins.
+		if c.cfg.OptIn && !rt.mcpIncluded {
+			continue
+		}
+		name := rt.mcpToolName
+		if name == "" {
+			name = rt.operationID
+		}
+		// Dedup both auto-generated and overridden names so a duplicate
+		// MCPTool override can't silently shadow another tool.
+		name = uniqueToolName(name, used)
+		used[name] = true
+		tools = append(tools, mcpTool{
+			name:        name,
+			description: toolDescription(rt),
+			inputSchema: buildInputSchema(rt),
+			authorizer:  rt.authorizer,
+			route:       rt,
+		})
+	}
+	for i := range tools {
+		index[tools[i].name] = &tools[i]
+	}
+	c.tools, c.index = tools, index
+	return tools, index
 }
 
 // toolList returns the tool definitions visible to the caller. When Authenticate
@@ -315,18 +328,24 @@ func (c *mcpController) build() {
 // (a tool without one is always visible). Without Authenticate there is no caller
 // identity to filter against, so the full list is returned (legacy behavior).
 func (c *mcpController) toolList(ctx HTTPContext) []map[string]interface{} {
-	c.build()
+	tools, _ := c.build()
 	filter := c.cfg.Authenticate != nil
-	out := make([]map[string]interface{}, 0, len(c.tools))
-	for _, t := range c.tools {
+	out := make([]map[string]interface{}, 0, len(tools))
+	for _, t := range tools {
 		if filter && t.authorizer != nil && !t.authorizer(ctx) {
 			continue
 		}
-		out = append(out, map[string]interface{}{
+		tool := map[string]interface{}{
 			"name":        t.name,
 			"description": t.description,
 			"inputSchema": t.inputSchema,
-		})
+		}
+		// Read-only routes (GET, or marked Route.ReadOnly) are advertised as
+		// such so clients can call them without a confirmation step.
+		if t.route.isReadOnly() {
+			tool["annotations"] = map[string]interface{}{"readOnlyHint": true}
+		}
+		out = append(out, tool)
 	}
 	return out
 }
@@ -383,6 +402,12 @@ func buildInputSchema(rt *Route) map[string]interface{} {
 	declaredPath := map[string]bool{}
 	for _, p := range rt.params {
 		schema := map[string]interface{}{"type": paramType(p.typ)}
+		if len(p.enum) > 0 {
+			schema["enum"] = enumValues(p)
+		}
+		if pattern := paramPattern(p); pattern != "" {
+			schema["pattern"] = pattern
+		}
 		if p.description != "" {
 			schema["description"] = p.description
 		}
@@ -395,16 +420,29 @@ func buildInputSchema(rt *Route) map[string]interface{} {
 		}
 	}
 	// Auto-extracted path params not explicitly declared via Route.Param.
+	pathParams := pathParamSpecs(rt.path)
 	for _, name := range extractPathParams(rt.path) {
 		if declaredPath[name] {
 			continue
 		}
-		properties[name] = map[string]interface{}{"type": "string"}
+		schema := map[string]interface{}{"type": pathParams[name].typ}
+		if pattern := paramPattern(pathParams[name]); pattern != "" {
+			schema["pattern"] = pattern
+		}
+		properties[name] = schema
 		required = append(required, name)
 	}
 
 	if rt.bodyType != nil {
-		properties["body"] = db.schemaFor(rt.bodyType)
+		body := db.schemaFor(rt.bodyType)
+		if examples := mediaExamples(rt.bodyType, rt.bodyExamples); examples != nil {
+			// A $ref's siblings are ignored by some validators, so wrap it.
+			if _, isRef := body["$ref"]; isRef {
+				body = map[string]interface{}{"allOf": []interface{}{body}}
+			}
+			body["examples"] = exampleValues(examples)
+		}
+		properties["body"] = body
 		required = append(required, "body")
 	}
 
@@ -427,6 +465,8 @@ func buildInputSchema(rt *Route) map[string]interface{} {
 		schema["$defs"] = defs
 	}
 	rewriteRefsToDefs(schema)
+	// MCP input schemas are plain JSON Schema, which has no nullable keyword.
+	convertSchemasTo31(schema)
 	return schema
 }
 
@@ -450,6 +490,14 @@ func rewriteRefsToDefs(v interface{}) {
 				}
 				continue
 			}
+			// A discriminator's mapping values are refs too.
+			if mapping, ok := val.(map[string]interface{}); ok && k == "mapping" {
+				for name, ref := range mapping {
+					if s, ok := ref.(string); ok && strings.HasPrefix(s, openapiRefPrefix) {
+						mapping[name] = jsonSchemaRefPrefix + strings.TrimPrefix(s, openapiRefPrefix)
+					}
+				}
+			}
 			rewriteRefsToDefs(val)
 		}
 	case []interface{}:
@@ -473,14 +521,24 @@ type callParams struct {
 	Arguments map[string]interface{} `json:"arguments"`
 }
 
-func (c *mcpController) invoke(httpReq *http.Request, params json.RawMessage) (map[string]interface{}, *rpcError) {
-	c.build()
+func (c *mcpController) invoke(ctx HTTPContext, params json.RawMessage) (result map[string]interface{}, rerr *rpcError) {
+	_, index := c.build()
+	httpReq := ctx.Request
 
 	var p callParams
 	if json.Unmarshal(params, &p) != nil {
 		return nil, &rpcError{Code: -32602, Message: "Invalid params"}
 	}
-	tool, ok := c.index[p.Name]
+	// Calls rejected before reaching a route are still audited; successful ones
+	// are recorded by the re-dispatched route itself.
+	if a := c.router.audit; a != nil {
+		defer func() {
+			if rerr != nil {
+				a.recordRejectedCall(ctx, p.Name, http.StatusBadRequest)
+			}
+		}()
+	}
+	tool, ok := index[p.Name]
 	if !ok {
 		return nil, &rpcError{Code: -32602, Message: fmt.Sprintf("Unknown tool: %s", p.Name)}
 	}
@@ -508,8 +566,9 @@ func (c *mcpController) invoke(httpReq *http.Request, params json.RawMessage) (m
 	// Build query string and collect declared header params from the arguments.
 	query := url.Values{}
 	headerArgs := map[string]string{}
+	cookieArgs := map[string]string{}
 	for _, pp := range rt.params {
-		if pp.in != "query" && pp.in != "header" {
+		if pp.in != "query" && pp.in != "header" && pp.in != "cookie" {
 			continue
 		}
 		s, ok, rerr := paramValue(args[pp.name])
@@ -519,10 +578,13 @@ func (c *mcpController) invoke(httpReq *http.Request, params json.RawMessage) (m
 		if !ok {
 			continue
 		}
-		if pp.in == "query" {
+		switch pp.in {
+		case "query":
 			query.Set(pp.name, s)
-		} else {
+		case "header":
 			headerArgs[pp.name] = s
+		case "cookie":
+			cookieArgs[pp.name] = s
 		}
 	}
 
@@ -556,9 +618,25 @@ func (c *mcpController) invoke(httpReq *http.Request, params json.RawMessage) (m
 	for name, v := range headerArgs {
 		synthReq.Header.Set(name, v)
 	}
+	// Likewise cookie parameters replace forwarded cookies of the same name.
+	if len(cookieArgs) > 0 {
+		cookies := synthReq.Cookies()
+		synthReq.Header.Del("Cookie")
+		for _, ck := range cookies {
+			if _, ok := cookieArgs[ck.Name]; !ok {
+				synthReq.AddCookie(ck)
+			}
+		}
+		for name, v := range cookieArgs {
+			synthReq.AddCookie(&http.Cookie{Name: name, Value: v})
+		}
+	}
+	if a := c.router.audit; a != nil {
+		synthReq = withAuditCall(synthReq, auditCall{tool: tool.name, actor: a.actorOf(ctx)})
+	}
 
 	rec := &responseRecorder{header: http.Header{}, status: http.StatusOK}
-	c.router.MuxRouter.ServeHTTP(rec, synthReq)
+	c.router.ServeHTTP(rec, synthReq)
 
 	text := rec.body.String()
 	if text == "" {
@@ -573,15 +651,14 @@ func (c *mcpController) invoke(httpReq *http.Request, params json.RawMessage) (m
 }
 
 // substitutePathParams replaces {name} / {name:regex} placeholders with their
-// URL-escaped values. It reuses pathParamRegex (openapi.go) rather than redefining
-// the pattern.
+// URL-escaped values. It reuses pathVars (openapi.go) rather than parsing the
+// template again.
 func substitutePathParams(path string, values map[string]string) string {
-	return pathParamRegex.ReplaceAllStringFunc(path, func(m string) string {
-		name := pathParamRegex.FindStringSubmatch(m)[1]
-		if v, ok := values[name]; ok {
-			return url.PathEscape(v)
+	return replacePathVars(path, func(v pathVar) string {
+		if val, ok := values[v.name]; ok {
+			return url.PathEscape(val)
 		}
-		return m
+		return path[v.start:v.end]
 	})
 }
 
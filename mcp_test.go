@@ -87,7 +87,7 @@ func rawRPC(t *testing.T, r *Router, body []byte, headers map[string]string) *ht
 		req.Header.Set(k, v)
 	}
 	rec := httptest.NewRecorder()
-	r.MuxRouter.ServeHTTP(rec, req)
+	r.ServeHTTP(rec, req)
 	return rec
 }
 
@@ -158,6 +158,17 @@ func TestMCP_ToolCall_GET(t *testing.T) {
 	assert.Equal(t, "42", w.ID)
 }
 
+func TestMCP_RuntimeRoutesBecomeTools(t *testing.T) {
+	r := mcpRouter()
+	assert.Nil(t, rpc(t, r, "tools/list", nil, nil).Error)
+
+	r.NewRestRouter("/late").Get("/", func(ctx HTTPContext) { ctx.SendJSON("late") })
+	resp := rpc(t, r, "tools/call", map[string]interface{}{"name": "get_late"}, nil)
+	assert.Nil(t, resp.Error)
+	text := resp.Result.(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})["text"]
+	assert.JSONEq(t, `"late"`, text.(string))
+}
+
 func TestMCP_ToolCall_InvalidBody(t *testing.T) {
 	r := mcpRouter()
 	// CreateWidget.Name is validate:"required"; empty body should fail validation.
@@ -516,3 +527,62 @@ func TestMCP_OptIn_DefaultExposesEverything(t *testing.T) {
 	assert.NotContains(t, names, "get_items_both")
 	assert.Len(t, c.tools, 2)
 }
+
+// A cookie parameter supplied in tool arguments replaces a forwarded cookie of
+// the same name and leaves the others intact.
+func TestMCP_CookieParamForwarded(t *testing.T) {
+	c := &mcpController{cfg: MCPConfig{Path: "/mcp"}}
+	r := newRouter(defaultBasePath, nil, []IHttpController{cookieParamController{}, c})
+
+	resp := rpc(t, r, "tools/call", map[string]interface{}{
+		"name":      "get_prefs_theme",
+		"arguments": map[string]interface{}{"theme": "dark"},
+	}, map[string]string{"Cookie": "theme=light; session=s1"})
+	assert.Nil(t, resp.Error)
+
+	text := resp.Result.(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})["text"].(string)
+	assert.Contains(t, text, `"theme":"dark"`)
+	assert.Contains(t, text, `"session":"s1"`)
+}
+
+type cookieParamController struct{}
+
+func (cookieParamController) Load(router *Router) {
+	router.NewRestRouter("/prefs").Get("/theme", func(ctx HTTPContext) {
+		ctx.SendJSON(map[string]string{"theme": ctx.GetCookie("theme"), "session": ctx.GetCookie("session")})
+	}).Cookie("theme", "string", false, "UI theme")
+}
+
+func TestMCP_ToolNamesDefaultToOperationID(t *testing.T) {
+	c := &mcpController{cfg: MCPConfig{Path: "/mcp"}}
+	newRouter(defaultBasePath, nil, []IHttpController{operationIDController{}, c})
+	c.build()
+
+	names := map[string]bool{}
+	for _, tool := range c.tools {
+		names[tool.name] = true
+	}
+	assert.Equal(t, map[string]bool{"get_ops_a_b": true, "get_ops_a_b_2": true, "get_ops_a_b_3": true, "make_op": true}, names)
+}
+
+func TestMCP_RuntimeRoutesShareOperationIDs(t *testing.T) {
+	c := &mcpController{cfg: MCPConfig{Path: "/mcp"}}
+	router := newRouter(defaultBasePath, nil, []IHttpController{operationIDController{}, c})
+	c.build()
+
+	r := router.NewRestRouter("/ops")
+	dup := r.Get("/{id}/b", func(ctx HTTPContext) {}).OperationID("createOp")
+	added := r.Get("/extra", func(ctx HTTPContext) {}).OperationID("getExtra")
+
+	// The duplicate explicit ID is rejected at registration, not at build.
+	assert.Equal(t, "get_ops_id_b", dup.operationID)
+	assert.Equal(t, "getExtra", added.operationID)
+
+	c.build()
+	names := map[string]bool{}
+	for _, tool := range c.tools {
+		names[tool.name] = true
+	}
+	assert.True(t, names["get_ops_id_b"])
+	assert.True(t, names["getExtra"])
+}
@@ -0,0 +1,335 @@
+package requiem
+
+import (
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMockScenarioHeader = "X-Mock-Scenario"
+	// mockMaxDepth bounds how deep recursive schemas are followed.
+	mockMaxDepth = 4
+)
+
+// MockConfig configures Server.UseMock.
+type MockConfig struct {
+	// Seed makes generated data deterministic: the same seed and request
+	// always get the same response.
+	Seed int64
+	// ScenarioHeader names the request header that selects the response.
+	// Defaults to X-Mock-Scenario. Its value is a declared status code
+	// ("404"), "error" for the route's first declared error response, or the
+	// name of an example ("example2", or a name from an
+	// OpenAPIExamplesProvider). Without it, the first declared 2xx response
+	// is served.
+	ScenarioHeader string
+}
+
+type mockController struct {
+	cfg MockConfig
+}
+
+func (c *mockController) Load(router *Router) {
+	if c.cfg.ScenarioHeader == "" {
+		c.cfg.ScenarioHeader = defaultMockScenarioHeader
+	}
+	router.mock = &c.cfg
+	if Logger != nil {
+		Logger.Warn("Mock mode is on: routes are served from their declared responses and handlers are not called")
+	}
+}
+
+// serveMock answers a request from the route's Returns declarations instead
+// of its handler, using examples where declared and generated data otherwise.
+func (r *Router) serveMock(ctx HTTPContext, rt *Route) {
+	cfg := r.mock
+	scenario := ctx.Request.Header.Get(cfg.ScenarioHeader)
+
+	codes := make([]int, 0, len(rt.responses))
+	for code := range rt.responses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	if len(codes) == 0 {
+		ctx.SendJSONWithStatus(map[string]string{
+			"message": fmt.Sprintf("%s %s declares no responses to mock", rt.method, stripPathRegex(rt.path)),
+		}, http.StatusNotImplemented)
+		return
+	}
+
+	status, example, found := mockSelect(rt, codes, scenario)
+	if !found {
+		ctx.SendJSONWithStatus(map[string]interface{}{
+			"message":  fmt.Sprintf("mock scenario %q is not declared for %s %s", scenario, rt.method, stripPathRegex(rt.path)),
+			"declared": codes,
+		}, http.StatusBadRequest)
+		return
+	}
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s %s?%s|%s", ctx.Request.Method, ctx.Request.URL.Path, ctx.Request.URL.RawQuery, scenario)
+	db := newDocBuilder()
+	gen := &mockData{schemas: db.schemas, rng: rand.New(rand.NewSource(cfg.Seed ^ int64(h.Sum64())))}
+
+	spec := rt.responses[status]
+	for _, hdr := range spec.headers {
+		ctx.Response.Header().Set(hdr.name, fmt.Sprint(gen.value(paramObject(hdr)["schema"].(map[string]interface{}), 0)))
+	}
+
+	mt := MediaTypeJSON
+	if len(rt.produces) > 0 {
+		mt = rt.produces[0]
+	}
+	if !isJSONMediaType(mt) {
+		ctx.SendData([]byte(fmt.Sprint(gen.value(db.mediaSchema(mt, spec.typ), 0))), mt, status)
+		return
+	}
+	if spec.typ == nil {
+		ctx.SendStatus(status)
+		return
+	}
+	if example == nil {
+		if examples := mediaExamples(spec.typ, rt.examples[status]); examples != nil {
+			example = exampleValues(examples)[0]
+		} else {
+			example = gen.value(db.schemaFor(spec.typ), 0)
+		}
+	}
+	ctx.SendJSONWithStatus(example, status)
+}
+
+// mockSelect picks the status, and the example if one is named, that a
+// scenario asks for.
+func mockSelect(rt *Route, codes []int, scenario string) (status int, example interface{}, found bool) {
+	switch {
+	case scenario == "":
+		for _, code := range codes {
+			if code >= 200 && code < 300 {
+				return code, nil, true
+			}
+		}
+		return codes[0], nil, true
+	case scenario == "error":
+		for _, code := range codes {
+			if code >= 400 {
+				return code, nil, true
+			}
+		}
+		return 0, nil, false
+	}
+	if code, err := strconv.Atoi(scenario); err == nil {
+		_, ok := rt.responses[code]
+		return code, nil, ok
+	}
+	for _, code := range codes {
+		examples := mediaExamples(rt.responses[code].typ, rt.examples[code])
+		if ex, ok := examples[scenario].(map[string]interface{}); ok {
+			return code, ex["value"], true
+		}
+	}
+	return 0, nil, false
+}
+
+// mockData generates values that satisfy the schemas schemaFor emits.
+type mockData struct {
+	schemas map[string]map[string]interface{}
+	rng     *rand.Rand
+}
+
+var mockWords = []string{
+	"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel",
+	"india", "juliet", "kilo", "lima", "mike", "november", "oscar", "papa",
+}
+
+// mockEpoch anchors generated timestamps so they don't depend on the clock.
+var mockEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (m *mockData) value(schema map[string]interface{}, depth int) interface{} {
+	if ex, ok := schema["example"]; ok {
+		return ex
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if depth >= mockMaxDepth {
+			return nil
+		}
+		return m.value(m.schemas[strings.TrimPrefix(ref, openapiRefPrefix)], depth+1)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[m.rng.Intn(len(enum))]
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		merged := map[string]interface{}{}
+		for _, s := range all {
+			v := m.value(s.(map[string]interface{}), depth)
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return v
+			}
+			for k, pv := range obj {
+				merged[k] = pv
+			}
+		}
+		return merged
+	}
+	if one, ok := schema["oneOf"].([]interface{}); ok && len(one) > 0 {
+		variant := one[m.rng.Intn(len(one))].(map[string]interface{})
+		v := m.value(variant, depth)
+		// Tag the variant with its discriminator value.
+		disc, _ := schema["discriminator"].(map[string]interface{})
+		mapping, _ := disc["mapping"].(map[string]interface{})
+		if obj, ok := v.(map[string]interface{}); ok {
+			for name, ref := range mapping {
+				if ref == variant["$ref"] {
+					obj[disc["propertyName"].(string)] = name
+				}
+			}
+		}
+		return v
+	}
+
+	switch schema["type"] {
+	case "string":
+		return m.str(schema)
+	case "integer":
+		return int64(math.Round(m.number(schema, true)))
+	case "number":
+		return m.number(schema, false)
+	case "boolean":
+		return m.rng.Intn(2) == 1
+	case "array":
+		n := 1 + m.rng.Intn(3)
+		if min, ok := schemaNumber(schema["minItems"]); ok && n < int(min) {
+			n = int(min)
+		}
+		if max, ok := schemaNumber(schema["maxItems"]); ok && n > int(max) {
+			n = int(max)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		out := make([]interface{}, 0, n)
+		for i := 0; i < n && items != nil; i++ {
+			if v := m.value(items, depth); v != nil {
+				out = append(out, v)
+			}
+		}
+		return out
+	case "object":
+		out := map[string]interface{}{}
+		props, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if v := m.value(props[name].(map[string]interface{}), depth); v != nil {
+				out[name] = v
+			}
+		}
+		if ap, ok := schema["additionalProperties"].(map[string]interface{}); ok && len(props) == 0 {
+			for i := 1; i <= 2; i++ {
+				out[fmt.Sprintf("key%d", i)] = m.value(ap, depth)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func (m *mockData) number(schema map[string]interface{}, integer bool) float64 {
+	min, max := 1.0, 1000.0
+	if v, ok := schemaNumber(schema["minimum"]); ok {
+		min = v
+		if max < min {
+			max = min + 1000
+		}
+	}
+	if v, ok := schemaNumber(schema["maximum"]); ok {
+		max = v
+		if min > max {
+			min = max - 1000
+		}
+	}
+	if ex, _ := schema["exclusiveMinimum"].(bool); ex {
+		min++
+	}
+	if ex, _ := schema["exclusiveMaximum"].(bool); ex {
+		max--
+	}
+	if max < min {
+		max = min
+	}
+	if max-min > 1e9 {
+		max = min + 1e9
+	}
+	if integer {
+		min, max = math.Ceil(min), math.Floor(max)
+		return min + float64(m.rng.Int63n(int64(max-min)+1))
+	}
+	return math.Round((min+m.rng.Float64()*(max-min))*100) / 100
+}
+
+func (m *mockData) str(schema map[string]interface{}) string {
+	n := m.rng.Intn(10000)
+	switch schema["format"] {
+	case "date-time":
+		return mockEpoch.Add(time.Duration(m.rng.Int63n(int64(365 * 24 * time.Hour)))).Format(time.RFC3339)
+	case "date":
+		return mockEpoch.AddDate(0, 0, m.rng.Intn(365)).Format("2006-01-02")
+	case "time":
+		return mockEpoch.Add(time.Duration(m.rng.Int63n(int64(24 * time.Hour)))).Format("15:04:05")
+	case "uuid":
+		b := make([]byte, 16)
+		m.rng.Read(b)
+		b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	case "email":
+		return fmt.Sprintf("%s%d@example.com", mockWords[m.rng.Intn(len(mockWords))], n)
+	case "uri":
+		return fmt.Sprintf("https://example.com/%s/%d", mockWords[m.rng.Intn(len(mockWords))], n)
+	case "hostname":
+		return mockWords[m.rng.Intn(len(mockWords))] + ".example.com"
+	case "ipv4", "ip":
+		return fmt.Sprintf("192.0.2.%d", 1+m.rng.Intn(254))
+	case "ipv6":
+		return fmt.Sprintf("2001:db8::%x", 1+m.rng.Intn(0xfffe))
+	case "byte":
+		return base64.StdEncoding.EncodeToString([]byte(mockWords[m.rng.Intn(len(mockWords))]))
+	case "binary":
+		return ""
+	}
+
+	s := mockWords[m.rng.Intn(len(mockWords))]
+	if pattern, ok := schema["pattern"].(string); ok {
+		if sample, ok := mockPatternSamples[pattern]; ok {
+			s = sample(m.rng)
+		}
+	}
+	if min, ok := schemaNumber(schema["minLength"]); ok && len(s) < int(min) {
+		s += strings.Repeat("x", int(min)-len(s))
+	}
+	if max, ok := schemaNumber(schema["maxLength"]); ok && len(s) > int(max) {
+		s = s[:int(max)]
+	}
+	return s
+}
+
+// mockPatternSamples generate strings for the patterns validatePatterns maps
+// rules to.
+var mockPatternSamples = map[string]func(*rand.Rand) string{
+	validatePatterns["alpha"]:       func(r *rand.Rand) string { return mockWords[r.Intn(len(mockWords))] },
+	validatePatterns["alphanum"]:    func(r *rand.Rand) string { return fmt.Sprintf("%s%d", mockWords[r.Intn(len(mockWords))], r.Intn(100)) },
+	validatePatterns["numeric"]:     func(r *rand.Rand) string { return strconv.Itoa(r.Intn(10000)) },
+	validatePatterns["number"]:      func(r *rand.Rand) string { return strconv.Itoa(r.Intn(10000)) },
+	validatePatterns["hexadecimal"]: func(r *rand.Rand) string { return fmt.Sprintf("%x", r.Intn(1<<24)) },
+	validatePatterns["hexcolor"]:    func(r *rand.Rand) string { return fmt.Sprintf("#%06x", r.Intn(1<<24)) },
+	validatePatterns["e164"]:        func(r *rand.Rand) string { return fmt.Sprintf("+1%010d", r.Int63n(1e10)) },
+	validatePatterns["uppercase"]:   func(r *rand.Rand) string { return strings.ToUpper(mockWords[r.Intn(len(mockWords))]) },
+}
@@ -0,0 +1,111 @@
+package requiem
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type MockGadget struct {
+	ID      string            `json:"id" validate:"uuid"`
+	Email   string            `json:"email" validate:"email"`
+	Name    string            `json:"name" validate:"required,min=3,max=5"`
+	Color   string            `json:"color" validate:"oneof=red green"`
+	Count   int               `json:"count" validate:"gte=10,lte=20"`
+	Code    string            `json:"code" validate:"hexcolor"`
+	Tags    []string          `json:"tags" validate:"max=2"`
+	Labels  map[string]string `json:"labels"`
+	Parent  *MockGadget       `json:"parent"`
+	Checked bool              `json:"checked"`
+}
+
+type mockedController struct{}
+
+func (mockedController) Load(router *Router) {
+	r := router.NewRestRouter("/gadgets")
+	fail := func(ctx HTTPContext) { panic("handler called in mock mode") }
+	r.Get("/{id}", fail).
+		Returns(200, MockGadget{}, "OK").
+		ReturnsHeader(200, "X-Rate-Limit", "integer", "Requests left").
+		Returns(404, ErrorBody{}, "Not found").
+		Returns(500, ErrorBody{}, "Oops")
+	r.Post("/", fail, CreateWidget{}).
+		Returns(201, Widget{}, "Created").
+		Example(201, Widget{ID: "w1", Name: "From example"}).
+		Example(201, Widget{ID: "w2", Name: "Second"})
+	r.Delete("/{id}", fail, nil).
+		Returns(204, nil, "Deleted")
+	r.Get("/", fail)
+}
+
+func mockRouter(cfg MockConfig) *Router {
+	return newRouter("/api", nil, []IHttpController{mockedController{}, &mockController{cfg: cfg}})
+}
+
+func TestMock_GeneratesSchemaValidData(t *testing.T) {
+	r := mockRouter(MockConfig{Seed: 7})
+
+	rec := serve(r, http.MethodGet, "/api/gadgets/1", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("X-Rate-Limit"))
+
+	res := &bufferedResponse{header: rec.Header(), status: rec.Code}
+	res.body.Write(rec.Body.Bytes())
+	assert.Empty(t, checkResponse(r.routes[0], res), rec.Body.String())
+
+	var g map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &g))
+	assert.Contains(t, g, "parent")
+
+	// Deterministic per seed and request.
+	assert.Equal(t, rec.Body.String(), serve(r, http.MethodGet, "/api/gadgets/1", "", nil).Body.String())
+	other := serve(mockRouter(MockConfig{Seed: 8}), http.MethodGet, "/api/gadgets/1", "", nil)
+	assert.NotEqual(t, rec.Body.String(), other.Body.String())
+}
+
+func TestMock_Scenarios(t *testing.T) {
+	r := mockRouter(MockConfig{})
+
+	rec := serve(r, http.MethodGet, "/api/gadgets/1", "", map[string]string{"X-Mock-Scenario": "404"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"message"`)
+
+	rec = serve(r, http.MethodGet, "/api/gadgets/1", "", map[string]string{"X-Mock-Scenario": "error"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(r, http.MethodGet, "/api/gadgets/1", "", map[string]string{"X-Mock-Scenario": "418"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `mock scenario \"418\" is not declared for GET /gadgets/{id}`)
+
+	rec = serve(r, http.MethodDelete, "/api/gadgets/1", "", nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = serve(r, http.MethodGet, "/api/gadgets/", "", nil)
+	assert.Equal(t, http.StatusNotImplemented, rec.Code)
+}
+
+func TestMock_Examples(t *testing.T) {
+	r := mockRouter(MockConfig{})
+
+	rec := serve(r, http.MethodPost, "/api/gadgets/", `{"name": "x"}`, nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"From example"`)
+
+	rec = serve(r, http.MethodPost, "/api/gadgets/", `{"name": "x"}`, map[string]string{"X-Mock-Scenario": "example2"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"Second"`)
+
+	// Bodies are still validated against the contract.
+	rec = serve(r, http.MethodPost, "/api/gadgets/", `{}`, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestMock_CustomScenarioHeader(t *testing.T) {
+	r := mockRouter(MockConfig{ScenarioHeader: "Prefer-Status"})
+
+	rec := serve(r, http.MethodGet, "/api/gadgets/1", "", map[string]string{"Prefer-Status": "500"})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -1,8 +1,13 @@
 package requiem
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
+	"math"
+	"mime"
 	"net/http"
 	"reflect"
 	"regexp"
@@ -10,6 +15,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -17,16 +25,67 @@ const (
 	defaultDocsPath = "/docs"
 )
 
+// Supported values for OpenAPIConfig.OpenAPIVersion.
+const (
+	OpenAPIVersion30 = "3.0.3"
+	OpenAPIVersion31 = "3.1.0"
+)
+
 type OpenAPIConfig struct {
-	Title       string
-	Version     string
-	Description string
-	Servers     []string
-	SpecPath    string
-	DocsPath    string
+	Title          string
+	Version        string
+	Description    string
+	TermsOfService string
+	Contact        *Contact
+	License        *License
+	// Servers are the server URLs, listed before ServerDetails, which can
+	// carry descriptions and URL variables.
+	Servers       []string
+	ServerDetails []APIServer
+	// Tags describes the tags routes use, in the order docs pages show them.
+	Tags         []Tag
+	ExternalDocs *ExternalDocs
+	// SpecPath is where the JSON document is served. The YAML variant is served
+	// next to it with a .yaml extension, and SpecPath itself also returns YAML
+	// to clients that Accept application/yaml.
+	SpecPath string
+	DocsPath string
+	// Docs configures the docs page: renderer, theme, logo and where its
+	// assets come from.
+	Docs DocsConfig
+	// ValidateRequests rejects requests whose path, query, header or cookie
+	// params don't match the route's declared types, required flags and enums
+	// with a 400 RequestValidationError, which also details body validation
+	// failures.
+	ValidateRequests bool
+	// ValidateResponses checks each response's status and JSON body against
+	// the route's Returns declarations, and logs, fails or records
+	// violations. Intended for development and tests.
+	ValidateResponses ResponseValidationMode
+	// OpenAPIVersion selects the emitted spec version: OpenAPIVersion30
+	// (default) or OpenAPIVersion31, which uses JSON Schema 2020-12 type unions
+	// (type: [..., "null"]) instead of nullable.
+	OpenAPIVersion string
+	// SecuritySchemes are emitted under components.securitySchemes, keyed by
+	// the names used in Security, RestRouter.Security and Route.Security.
+	SecuritySchemes map[string]SecurityScheme
+	// Security is the document-wide default security requirement.
+	Security []SecurityRequirement
+}
+
+func (cfg OpenAPIConfig) openapiVersion() string {
+	if cfg.OpenAPIVersion == "" {
+		return OpenAPIVersion30
+	}
+	return cfg.OpenAPIVersion
+}
+
+func (cfg OpenAPIConfig) is31() bool {
+	return strings.HasPrefix(cfg.openapiVersion(), "3.1")
 }
 
 type Route struct {
+	router       *Router
 	method       string
 	path         string
 	routerPrefix string
@@ -42,11 +101,24 @@ type Route struct {
 	mcpIncluded  bool
 	mcpToolName  string
 	authorizer   Authorizer
+	readOnly     bool
+	examples     map[int][]interface{}
+	bodyExamples []interface{}
+	consumes     []string
+	produces     []string
+	security     []SecurityRequirement
+	securitySet  bool
+	public       bool
+	operationID  string
+	explicitOpID bool
+	callbacks    []callbackSpec
+	externalDocs *ExternalDocs
 }
 
 type responseSpec struct {
 	typ         reflect.Type
 	description string
+	headers     []paramSpec
 }
 
 type paramSpec struct {
@@ -55,6 +127,9 @@ type paramSpec struct {
 	typ         string
 	required    bool
 	description string
+	enum        []string
+	// pattern is the mux regex constraining a path param.
+	pattern string
 }
 
 func (rt *Route) Summary(s string) *Route {
@@ -85,7 +160,20 @@ func (rt *Route) Returns(status int, v interface{}, description string) *Route {
 	if v != nil {
 		t = reflect.TypeOf(v)
 	}
-	rt.responses[status] = responseSpec{typ: t, description: description}
+	rt.responses[status] = responseSpec{typ: t, description: description, headers: rt.responses[status].headers}
+	return rt
+}
+
+// ReturnsHeader documents a header set on the response with the given status,
+// e.g. Location on a 201 or Retry-After on a 429. Document cookies the
+// response sets as a Set-Cookie header.
+func (rt *Route) ReturnsHeader(status int, name, typ, description string) *Route {
+	if rt.responses == nil {
+		rt.responses = make(map[int]responseSpec)
+	}
+	r := rt.responses[status]
+	r.headers = append(r.headers, paramSpec{name: name, in: "header", typ: typ, description: description})
+	rt.responses[status] = r
 	return rt
 }
 
@@ -99,11 +187,64 @@ func (rt *Route) Header(name, typ string, required bool, description string) *Ro
 	return rt
 }
 
+// Cookie documents a cookie the route reads.
+func (rt *Route) Cookie(name, typ string, required bool, description string) *Route {
+	rt.params = append(rt.params, paramSpec{name: name, in: "cookie", typ: typ, required: required, description: description})
+	return rt
+}
+
+// Param documents a path param. Its mux pattern, if any, is kept as the
+// param's pattern; undeclared path params are documented from the pattern
+// alone.
 func (rt *Route) Param(name, typ, description string) *Route {
-	rt.params = append(rt.params, paramSpec{name: name, in: "path", typ: typ, required: true, description: description})
+	pattern := pathParamSpecs(rt.path)[name].pattern
+	rt.params = append(rt.params, paramSpec{name: name, in: "path", typ: typ, required: true, description: description, pattern: pattern})
 	return rt
 }
 
+// OperationID overrides the route's operationId, which otherwise derives from
+// its method and path like MCP tool names (GET /widgets/{id} ->
+// "get_widgets_id"). Explicit IDs must be unique across the server; a
+// duplicate is logged and ignored, leaving the derived ID in place.
+func (rt *Route) OperationID(id string) *Route {
+	r := rt.router
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, other := range r.routes {
+		if other != rt && other.explicitOpID && other.operationID == id {
+			if Logger != nil {
+				Logger.Error("[%s] %s => Duplicate operationId %q, already used by [%s] %s", rt.method, rt.path, id, other.method, other.path)
+			}
+			return rt
+		}
+	}
+	rt.operationID = id
+	rt.explicitOpID = true
+	r.assignOperationIDs()
+	r.generation++
+	return rt
+}
+
+// assignOperationIDs gives every route an operationId, which the spec and the
+// MCP tool names share. Explicit IDs, unique by construction, are claimed
+// first; the derived ones are then deduplicated around them in registration
+// order. Callers hold r.mu.
+func (r *Router) assignOperationIDs() {
+	used := map[string]bool{}
+	for _, rt := range r.routes {
+		if rt.explicitOpID {
+			used[rt.operationID] = true
+		}
+	}
+	for _, rt := range r.routes {
+		if rt.explicitOpID {
+			continue
+		}
+		rt.operationID = uniqueToolName(defaultToolName(rt), used)
+		used[rt.operationID] = true
+	}
+}
+
 func (rt *Route) ExcludeFromSpec() *Route {
 	rt.excluded = true
 	return rt
@@ -112,13 +253,51 @@ func (rt *Route) ExcludeFromSpec() *Route {
 type openapiController struct {
 	cfg    OpenAPIConfig
 	router *Router
+	// violations, when set, replaces the router's own record of response
+	// violations, so Server can read it.
+	violations *responseViolations
+
+	// mu guards the cached spec, which is rebuilt whenever the router's
+	// generation moves past the one it was built from.
+	mu         sync.Mutex
+	built      bool
+	generation uint64
+	spec       specRepresentation
+	specYAML   specRepresentation
+	modified   time.Time
+}
 
-	once sync.Once
-	spec []byte
+// specRepresentation is one encoding of the spec, pre-compressed, with the
+// ETags of both forms.
+type specRepresentation struct {
+	body     []byte
+	gzipped  []byte
+	etag     string
+	gzipETag string
+}
+
+func newSpecRepresentation(body []byte) specRepresentation {
+	sum := sha256.Sum256(body)
+	tag := hex.EncodeToString(sum[:16])
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(body)
+	zw.Close()
+	return specRepresentation{
+		body:     body,
+		gzipped:  buf.Bytes(),
+		etag:     `"` + tag + `"`,
+		gzipETag: `"` + tag + `-gzip"`,
+	}
 }
 
 func (c *openapiController) Load(router *Router) {
 	c.router = router
+	router.validateRequests = c.cfg.ValidateRequests
+	router.validateResponses = c.cfg.ValidateResponses
+	if c.violations != nil {
+		router.violations = c.violations
+	}
 
 	prefix := commonRoutePrefix(router.routes)
 
@@ -131,9 +310,14 @@ func (c *openapiController) Load(router *Router) {
 		docsPath = prefix + defaultDocsPath
 	}
 
-	router.MuxRouter.HandleFunc(specPath, c.serveSpec).Methods(http.MethodGet)
+	if yamlPath := yamlSpecPath(specPath); yamlPath == specPath {
+		router.MuxRouter.HandleFunc(specPath, c.serveSpecYAML).Methods(http.MethodGet)
+	} else {
+		router.MuxRouter.HandleFunc(specPath, c.serveSpec).Methods(http.MethodGet)
+		router.MuxRouter.HandleFunc(yamlPath, c.serveSpecYAML).Methods(http.MethodGet)
+	}
 	if docsPath != "-" {
-		router.MuxRouter.HandleFunc(docsPath, c.serveDocs(specPath, docsPath)).Methods(http.MethodGet)
+		c.mountDocs(router, specPath, docsPath)
 	}
 }
 
@@ -206,25 +390,131 @@ func commonRoutePrefix(routes []*Route) string {
 	return "/" + strings.Join(common, "/")
 }
 
+// yamlSpecPath returns the path of the YAML variant of the spec served at
+// specPath: "/openapi.json" -> "/openapi.yaml", "/spec" -> "/spec.yaml". A
+// specPath that is already YAML is returned as is.
+func yamlSpecPath(specPath string) string {
+	if strings.HasSuffix(specPath, ".yaml") || strings.HasSuffix(specPath, ".yml") {
+		return specPath
+	}
+	return strings.TrimSuffix(specPath, ".json") + ".yaml"
+}
+
+// wantsYAML reports whether the request's Accept header ranks a YAML media
+// type above JSON. Wildcards count for neither, so JSON stays the default.
+func wantsYAML(r *http.Request) bool {
+	var yamlQ, jsonQ float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		_, sub, _ := strings.Cut(mt, "/")
+		switch {
+		case sub == "yaml" || sub == "x-yaml" || strings.HasSuffix(sub, "+yaml"):
+			yamlQ = math.Max(yamlQ, q)
+		case sub == "json" || strings.HasSuffix(sub, "+json"):
+			jsonQ = math.Max(jsonQ, q)
+		}
+	}
+	return yamlQ > 0 && yamlQ > jsonQ
+}
+
+// build returns the cached spec, first rebuilding it if routes were
+// registered or Router.InvalidateSpec was called since it was last built.
+// modified only moves when the document actually changes, so clients holding
+// a copy keep getting 304s across no-op rebuilds.
+func (c *openapiController) build() (spec, specYAML specRepresentation, modified time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.router.mu.RLock()
+	stale := !c.built || c.generation != c.router.generation
+	c.router.mu.RUnlock()
+	if !stale {
+		return c.spec, c.specYAML, c.modified
+	}
+
+	c.router.mu.RLock()
+	routes := append([]*Route(nil), c.router.routes...)
+	c.generation = c.router.generation
+	c.router.mu.RUnlock()
+
+	if !c.built {
+		warnUnknownSchemes(c.cfg, routes)
+	}
+	c.built = true
+	doc := buildDoc(c.cfg, routes, c.router.webhooks...)
+	if bytes.Equal(doc, c.spec.body) {
+		return c.spec, c.specYAML, c.modified
+	}
+	docYAML, err := specToYAML(doc)
+	if err != nil {
+		Logger.Error("Could not render OpenAPI spec as YAML: %s", err)
+	}
+	c.spec = newSpecRepresentation(doc)
+	c.specYAML = newSpecRepresentation(docYAML)
+	c.modified = time.Now().UTC().Truncate(time.Second)
+	return c.spec, c.specYAML, c.modified
+}
+
 func (c *openapiController) serveSpec(w http.ResponseWriter, r *http.Request) {
-	c.once.Do(func() {
-		c.spec = buildDoc(c.cfg, c.router.routes)
-	})
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(c.spec)
+	if wantsYAML(r) {
+		c.serveSpecYAML(w, r)
+		return
+	}
+	spec, _, modified := c.build()
+	serveSpecRepresentation(w, r, spec, "application/json", modified)
 }
 
-func (c *openapiController) serveDocs(specPath, docsPath string) http.HandlerFunc {
-	title := c.cfg.Title
-	if title == "" {
-		title = "API"
+func (c *openapiController) serveSpecYAML(w http.ResponseWriter, r *http.Request) {
+	_, specYAML, modified := c.build()
+	serveSpecRepresentation(w, r, specYAML, "application/yaml", modified)
+}
+
+// serveSpecRepresentation writes rep, gzipped for clients that accept it,
+// with the validators http.ServeContent needs to answer conditional requests
+// with a 304. Cache-Control: no-cache makes caches revalidate every time, so
+// a rebuilt spec is picked up immediately.
+func serveSpecRepresentation(w http.ResponseWriter, r *http.Request, rep specRepresentation, contentType string, modified time.Time) {
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("Vary", "Accept, Accept-Encoding")
+	h.Set("Cache-Control", "no-cache")
+	body := rep.body
+	if acceptsGzip(r) {
+		body = rep.gzipped
+		h.Set("Content-Encoding", "gzip")
+		h.Set("ETag", rep.gzipETag)
+	} else {
+		h.Set("ETag", rep.etag)
 	}
-	return func(w http.ResponseWriter, r *http.Request) {
-		base := strings.TrimSuffix(r.URL.Path, docsPath)
-		specURL := base + specPath
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprintf(w, swaggerUIHTML, title, specURL)
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		for _, p := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if q, err := strconv.ParseFloat(v, 64); strings.TrimSpace(k) == "q" && err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
 	}
+	return false
 }
 
 type docBuilder struct {
@@ -235,7 +525,7 @@ func newDocBuilder() *docBuilder {
 	return &docBuilder{schemas: make(map[string]map[string]interface{})}
 }
 
-func buildDoc(cfg OpenAPIConfig, routes []*Route) []byte {
+func buildDoc(cfg OpenAPIConfig, routes []*Route, webhooks ...*Webhook) []byte {
 	db := newDocBuilder()
 
 	paths := map[string]map[string]interface{}{}
@@ -250,41 +540,167 @@ func buildDoc(cfg OpenAPIConfig, routes []*Route) []byte {
 		paths[openapiPath][strings.ToLower(rt.method)] = db.buildOperation(rt)
 	}
 
-	info := map[string]interface{}{
-		"title":   cfg.Title,
-		"version": cfg.Version,
-	}
-	if cfg.Description != "" {
-		info["description"] = cfg.Description
-	}
-
 	spec := map[string]interface{}{
-		"openapi": "3.0.3",
-		"info":    info,
+		"openapi": cfg.openapiVersion(),
+		"info":    infoObject(cfg),
 		"paths":   paths,
 	}
 
-	if len(cfg.Servers) > 0 {
-		servers := make([]map[string]interface{}, 0, len(cfg.Servers))
-		for _, u := range cfg.Servers {
-			servers = append(servers, map[string]interface{}{"url": u})
+	if len(webhooks) > 0 {
+		// 3.0 has no webhooks field; tools that support them read the
+		// extension instead.
+		key := "x-webhooks"
+		if cfg.is31() {
+			key = "webhooks"
 		}
+		spec[key] = db.buildWebhooks(webhooks)
+	}
+
+	if servers := serverObjects(cfg); len(servers) > 0 {
 		spec["servers"] = servers
 	}
+	if tags := tagObjects(cfg, routes); len(tags) > 0 {
+		spec["tags"] = tags
+	}
+	if cfg.ExternalDocs != nil {
+		spec["externalDocs"] = externalDocsObject(cfg.ExternalDocs)
+	}
+
+	if len(cfg.Security) > 0 {
+		spec["security"] = cfg.Security
+	}
 
+	components := map[string]interface{}{}
 	if len(db.schemas) > 0 {
-		spec["components"] = map[string]interface{}{
-			"schemas": db.schemas,
+		components["schemas"] = db.schemas
+	}
+	if len(cfg.SecuritySchemes) > 0 {
+		schemes := make(map[string]interface{}, len(cfg.SecuritySchemes))
+		for name, scheme := range cfg.SecuritySchemes {
+			schemes[name] = securitySchemeObject(scheme)
 		}
+		components["securitySchemes"] = schemes
+	}
+	if len(components) > 0 {
+		spec["components"] = components
 	}
 
 	b, _ := json.MarshalIndent(spec, "", "  ")
+	if cfg.is31() {
+		// Round-trip through JSON so nested typed maps become plain
+		// map[string]interface{} values that can be rewritten uniformly.
+		var generic map[string]interface{}
+		json.Unmarshal(b, &generic)
+		convertDocTo31(generic)
+		b, _ = json.MarshalIndent(generic, "", "  ")
+	}
 	return b
 }
 
+// convertSchemasTo31 rewrites OpenAPI 3.0 schema idioms in place to their
+// JSON Schema 2020-12 equivalents used by OpenAPI 3.1: "nullable: true" becomes
+// a "null" member of the type union, or an anyOf with {"type": "null"} when the
+// schema has no type of its own (e.g. a $ref wrapped in allOf), and boolean
+// exclusiveMinimum/exclusiveMaximum flags become numeric bounds.
+func convertSchemasTo31(v interface{}) {
+	schema, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	// Only recurse into keywords whose values are schemas: example, default,
+	// enum and const hold user data.
+	for _, k := range []string{"items", "additionalProperties", "not", "contains", "propertyNames", "if", "then", "else"} {
+		convertSchemasTo31(schema[k])
+	}
+	for _, k := range []string{"allOf", "anyOf", "oneOf", "prefixItems"} {
+		list, _ := schema[k].([]interface{})
+		for _, s := range list {
+			convertSchemasTo31(s)
+		}
+	}
+	for _, k := range []string{"properties", "patternProperties", "$defs"} {
+		m, _ := schema[k].(map[string]interface{})
+		for _, s := range m {
+			convertSchemasTo31(s)
+		}
+	}
+
+	// 3.0's boolean exclusive flags become the bound itself in 3.1. A flag
+	// without its bound has nothing to exclude and is dropped.
+	for flag, bound := range map[string]string{"exclusiveMinimum": "minimum", "exclusiveMaximum": "maximum"} {
+		if exclusive, ok := schema[flag].(bool); ok {
+			delete(schema, flag)
+			if b, ok := schema[bound]; exclusive && ok {
+				schema[flag] = b
+				delete(schema, bound)
+			}
+		}
+	}
+	if nullable, ok := schema["nullable"].(bool); ok {
+		delete(schema, "nullable")
+		if !nullable {
+			return
+		}
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []interface{}{typ, "null"}
+			return
+		}
+		inner := make(map[string]interface{}, len(schema))
+		for k, val := range schema {
+			inner[k] = val
+			delete(schema, k)
+		}
+		schema["anyOf"] = []interface{}{inner, map[string]interface{}{"type": "null"}}
+	}
+}
+
+// convertDocTo31 applies convertSchemasTo31 to every schema of an OpenAPI
+// document: components.schemas and the values of "schema" keys under paths,
+// webhooks and components. Examples and extensions are left alone.
+func convertDocTo31(doc map[string]interface{}) {
+	if components, ok := doc["components"].(map[string]interface{}); ok {
+		schemas, _ := components["schemas"].(map[string]interface{})
+		for _, s := range schemas {
+			convertSchemasTo31(s)
+		}
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for k, val := range t {
+				switch {
+				case k == "example" || k == "examples" || strings.HasPrefix(k, "x-"):
+				case k == "schema":
+					convertSchemasTo31(val)
+				default:
+					walk(val)
+				}
+			}
+		case []interface{}:
+			for _, e := range t {
+				walk(e)
+			}
+		}
+	}
+	for _, k := range []string{"paths", "webhooks"} {
+		walk(doc[k])
+	}
+	if components, ok := doc["components"].(map[string]interface{}); ok {
+		for k, v := range components {
+			if k != "schemas" {
+				walk(v)
+			}
+		}
+	}
+}
+
 func (db *docBuilder) buildOperation(rt *Route) map[string]interface{} {
 	op := map[string]interface{}{}
 
+	if rt.operationID != "" {
+		op["operationId"] = rt.operationID
+	}
 	if rt.summary != "" {
 		op["summary"] = rt.summary
 	}
@@ -297,8 +713,14 @@ func (db *docBuilder) buildOperation(rt *Route) map[string]interface{} {
 	if rt.deprecated {
 		op["deprecated"] = true
 	}
+	if rt.externalDocs != nil {
+		op["externalDocs"] = externalDocsObject(rt.externalDocs)
+	}
+	if security := operationSecurity(rt); security != nil {
+		op["security"] = security
+	}
 
-	pathParams := extractPathParams(rt.path)
+	pathParams := pathParamSpecs(rt.path)
 	parameters := []map[string]interface{}{}
 	declaredPath := map[string]bool{}
 
@@ -308,30 +730,35 @@ func (db *docBuilder) buildOperation(rt *Route) map[string]interface{} {
 		}
 		parameters = append(parameters, paramObject(p))
 	}
-	for _, name := range pathParams {
+	for _, name := range extractPathParams(rt.path) {
 		if declaredPath[name] {
 			continue
 		}
-		parameters = append(parameters, paramObject(paramSpec{
-			name:     name,
-			in:       "path",
-			typ:      "string",
-			required: true,
-		}))
+		parameters = append(parameters, paramObject(pathParams[name]))
 	}
 
 	if len(parameters) > 0 {
 		op["parameters"] = parameters
 	}
 
-	if rt.bodyType != nil {
+	if rt.bodyType != nil || len(rt.consumes) > 0 {
+		consumes := rt.consumes
+		if len(consumes) == 0 {
+			consumes = []string{MediaTypeJSON}
+		}
+		content := map[string]interface{}{}
+		for _, mt := range consumes {
+			media := map[string]interface{}{
+				"schema": db.mediaSchema(mt, rt.bodyType),
+			}
+			if examples := mediaExamples(rt.bodyType, rt.bodyExamples); examples != nil && isJSONMediaType(mt) {
+				media["examples"] = examples
+			}
+			content[mt] = media
+		}
 		op["requestBody"] = map[string]interface{}{
 			"required": true,
-			"content": map[string]interface{}{
-				"application/json": map[string]interface{}{
-					"schema": db.schemaFor(rt.bodyType),
-				},
-			},
+			"content":  content,
 		}
 	}
 
@@ -353,31 +780,73 @@ func (db *docBuilder) buildOperation(rt *Route) map[string]interface{} {
 				desc = http.StatusText(code)
 			}
 			resp := map[string]interface{}{"description": desc}
-			if r.typ != nil {
-				resp["content"] = map[string]interface{}{
-					"application/json": map[string]interface{}{
-						"schema": db.schemaFor(r.typ),
-					},
+			if content := db.responseContent(rt, code, r.typ); len(content) > 0 {
+				resp["content"] = content
+			}
+			if len(r.headers) > 0 {
+				headers := map[string]interface{}{}
+				for _, h := range r.headers {
+					header := paramObject(h)
+					delete(header, "name")
+					delete(header, "in")
+					delete(header, "required")
+					headers[h.name] = header
 				}
+				resp["headers"] = headers
 			}
 			responses[strconv.Itoa(code)] = resp
 		}
 	}
 	op["responses"] = responses
 
+	if len(rt.callbacks) > 0 {
+		op["callbacks"] = db.buildCallbacks(rt.callbacks)
+	}
+
 	return op
 }
 
+// responseContent lists a response's body under every media type the route
+// produces (JSON by default). Untyped responses only list non-JSON types, and
+// never for 204s.
+func (db *docBuilder) responseContent(rt *Route, code int, t reflect.Type) map[string]interface{} {
+	produces := rt.produces
+	if len(produces) == 0 {
+		produces = []string{MediaTypeJSON}
+	}
+	content := map[string]interface{}{}
+	for _, mt := range produces {
+		if t == nil && (isJSONMediaType(mt) || code == http.StatusNoContent) {
+			continue
+		}
+		media := map[string]interface{}{
+			"schema": db.mediaSchema(mt, t),
+		}
+		if examples := mediaExamples(t, rt.examples[code]); examples != nil && isJSONMediaType(mt) {
+			media["examples"] = examples
+		}
+		content[mt] = media
+	}
+	return content
+}
+
 func paramObject(p paramSpec) map[string]interface{} {
 	typ := p.typ
 	if typ == "" {
 		typ = "string"
 	}
+	schema := map[string]interface{}{"type": typ}
+	if len(p.enum) > 0 {
+		schema["enum"] = enumValues(p)
+	}
+	if pattern := paramPattern(p); pattern != "" {
+		schema["pattern"] = pattern
+	}
 	obj := map[string]interface{}{
 		"name":     p.name,
 		"in":       p.in,
 		"required": p.required,
-		"schema":   map[string]interface{}{"type": typ},
+		"schema":   schema,
 	}
 	if p.description != "" {
 		obj["description"] = p.description
@@ -385,17 +854,132 @@ func paramObject(p paramSpec) map[string]interface{} {
 	return obj
 }
 
-var pathParamRegex = regexp.MustCompile(`\{([^:}]+)(?::[^}]+)?\}`)
+// pathVar is a {name} or {name:pattern} variable in a mux path template.
+type pathVar struct {
+	name    string
+	pattern string
+	// start and end are the offsets of its braces in the path.
+	start, end int
+}
+
+// pathVars scans a mux path template for its variables. Patterns may contain
+// braces of their own, e.g. {code:[A-Z]{3}}, so braces are matched by depth
+// like mux does rather than with a regular expression.
+func pathVars(path string) []pathVar {
+	var vars []pathVar
+	depth, start := 0, 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			if depth--; depth == 0 {
+				name, pattern, _ := strings.Cut(path[start+1:i], ":")
+				vars = append(vars, pathVar{name: name, pattern: pattern, start: start, end: i + 1})
+			}
+		}
+	}
+	return vars
+}
 
 func extractPathParams(path string) []string {
-	matches := pathParamRegex.FindAllStringSubmatch(path, -1)
-	names := make([]string, 0, len(matches))
-	for _, m := range matches {
-		names = append(names, m[1])
+	vars := pathVars(path)
+	names := make([]string, 0, len(vars))
+	for _, v := range vars {
+		names = append(names, v.name)
 	}
 	return names
 }
 
+// replacePathVars rewrites each variable in path with repl's result.
+func replacePathVars(path string, repl func(v pathVar) string) string {
+	var b strings.Builder
+	last := 0
+	for _, v := range pathVars(path) {
+		b.WriteString(path[last:v.start])
+		b.WriteString(repl(v))
+		last = v.end
+	}
+	b.WriteString(path[last:])
+	return b.String()
+}
+
 func stripPathRegex(path string) string {
-	return pathParamRegex.ReplaceAllString(path, "{$1}")
+	return replacePathVars(path, func(v pathVar) string { return "{" + v.name + "}" })
+}
+
+// numericPattern matches mux patterns that only accept unsigned integers,
+// such as [0-9]+, \d{1,6} or [1-9][0-9]*.
+var numericPattern = regexp.MustCompile(`^(?:(?:\[[0-9]-[0-9]\]|\\d|[0-9])(?:[+*?]|\{[0-9]+(?:,[0-9]*)?\})?)+$`)
+
+// pathParamSpecs describes the path's variables as path params: integers
+// when their pattern only admits digits, strings otherwise, carrying the
+// pattern either way.
+func pathParamSpecs(path string) map[string]paramSpec {
+	specs := map[string]paramSpec{}
+	for _, v := range pathVars(path) {
+		p := paramSpec{name: v.name, in: "path", typ: "string", required: true, pattern: v.pattern}
+		if numericPattern.MatchString(v.pattern) {
+			p.typ = "integer"
+		}
+		specs[v.name] = p
+	}
+	return specs
+}
+
+// paramPattern returns the schema pattern for a param's mux pattern. JSON
+// Schema patterns only constrain strings, so typed params such as inferred
+// integers don't carry one.
+func paramPattern(p paramSpec) string {
+	if p.pattern == "" || (p.typ != "" && p.typ != "string") {
+		return ""
+	}
+	return schemaPattern(p.pattern)
+}
+
+// schemaPattern anchors a mux pattern, which must match the whole segment,
+// for use as a JSON Schema pattern, which matches anywhere in the value.
+func schemaPattern(pattern string) string {
+	if strings.Contains(pattern, "|") {
+		pattern = "(?:" + pattern + ")"
+	}
+	return "^" + pattern + "$"
+}
+
+// specToYAML renders a JSON spec document as block-style YAML. It goes through
+// a yaml.Node rather than a Go map so key order is preserved, and clears the
+// JSON (flow/quoted) styles so the encoder picks plain YAML styles, quoting
+// only where a scalar would otherwise change type (e.g. "200", "1.0").
+func specToYAML(spec []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+	var reset func(n *yaml.Node)
+	reset = func(n *yaml.Node) {
+		if len(n.Content) > 0 || n.Kind == yaml.ScalarNode {
+			n.Style = 0
+		}
+		for _, c := range n.Content {
+			reset(c)
+		}
+	}
+	reset(&doc)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
@@ -1,14 +1,19 @@
 package requiem
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 type Widget struct {
@@ -207,7 +212,37 @@ func TestOpenAPI_PathRegexStripped(t *testing.T) {
 	spec := string(buildDoc(OpenAPIConfig{Title: "T", Version: "1"}, router.routes))
 
 	assert.Contains(t, spec, "/thing/{id}")
-	assert.NotContains(t, spec, "[0-9]+")
+	assert.Contains(t, spec, "/thing/code/{code}")
+	assert.NotContains(t, spec, "{id:")
+}
+
+func TestOpenAPI_PathRegexBecomesPattern(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{&regexController{}})
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buildDoc(OpenAPIConfig{Title: "T", Version: "1"}, router.routes), &spec))
+	paths := spec["paths"].(map[string]interface{})
+	param := func(path string) map[string]interface{} {
+		op := paths[path].(map[string]interface{})["get"].(map[string]interface{})
+		return op["parameters"].([]interface{})[0].(map[string]interface{})
+	}
+
+	assert.Equal(t, map[string]interface{}{"type": "integer"}, param("/thing/{id}")["schema"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "pattern": "^[A-Z]{3}$"}, param("/thing/code/{code}")["schema"])
+	declared := param("/thing/kind/{kind}")
+	assert.Equal(t, "Kind of thing", declared["description"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "pattern": "^(?:big|small)$"}, declared["schema"])
+
+	input := buildInputSchema(router.routes[0])["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "integer"}, input["id"])
+}
+
+func TestNumericPattern(t *testing.T) {
+	for _, p := range []string{"[0-9]+", `\d+`, `\d{1,6}`, "[1-9][0-9]*", "[0-9]{4}"} {
+		assert.True(t, numericPattern.MatchString(p), p)
+	}
+	for _, p := range []string{"", "[a-z0-9]+", "-?[0-9]+", "[0-9]+\\.[0-9]+", "[0-9a-f]{8}"} {
+		assert.False(t, numericPattern.MatchString(p), p)
+	}
 }
 
 type regexController struct{}
@@ -215,8 +250,53 @@ type regexController struct{}
 func (c *regexController) Load(router *Router) {
 	r := router.NewRestRouter("/thing")
 	r.Get("/{id:[0-9]+}", func(ctx HTTPContext) {
-		ctx.SendStatus(http.StatusOK)
+		id, err := ctx.GetParamInt("id")
+		if err != nil {
+			ctx.SendJSONWithStatus(err, http.StatusBadRequest)
+			return
+		}
+		ctx.SendJSON(id)
 	}).Summary("Get thing")
+	r.Get("/code/{code:[A-Z]{3}}", func(ctx HTTPContext) {
+		_, err := ctx.GetParamInt("code")
+		ctx.SendJSONWithStatus(err, http.StatusBadRequest)
+	})
+	r.Get("/kind/{kind:big|small}", func(ctx HTTPContext) {}).
+		Param("kind", "string", "Kind of thing")
+}
+
+func TestHTTPContext_GetParamInt(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{&regexController{}})
+	get := func(path string) *httptest.ResponseRecorder {
+		return serve(router, http.MethodGet, path, "", nil)
+	}
+
+	rec := get("/api/thing/42")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "42\n", rec.Body.String())
+
+	rec = get("/api/thing/99999999999999999999")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"in": "path", "name": "id", "reason": "must be of type integer, got \"99999999999999999999\""}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/api/thing/abc").Code)
+	assert.Contains(t, get("/api/thing/code/ABC").Body.String(), `"reason":"must be of type integer, got \"ABC\""`)
+}
+
+func TestHTTPContext_TypedParamErrors(t *testing.T) {
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/", nil), map[string]string{"n": "99999999999999999999", "f": "1e999", "b": "maybe"})
+	ctx := newHTTPContext(httptest.NewRecorder(), req, nil, nil, nil)
+
+	_, intErr := ctx.GetParamInt("n")
+	_, floatErr := ctx.GetParamFloat("f")
+	_, boolErr := ctx.GetParamBool("b")
+	_, missingErr := ctx.GetParamInt("missing")
+	for _, err := range []error{intErr, floatErr, boolErr, missingErr} {
+		var pe *ParamError
+		assert.ErrorAs(t, err, &pe)
+	}
+	assert.EqualError(t, intErr, `path param n must be of type integer, got "99999999999999999999"`)
+	assert.EqualError(t, missingErr, "path param missing is required")
 }
 
 func TestOpenAPI_ExcludesItsOwnRoutes(t *testing.T) {
@@ -508,3 +588,425 @@ func TestOpenAPI_EmbeddedWithExplicitJSONTagStaysNested(t *testing.T) {
 	assert.NotContains(t, props, "id")
 	assert.NotContains(t, props, "title")
 }
+
+func specRouter(cfg OpenAPIConfig, controllers ...IHttpController) *Router {
+	return newRouter("/api", nil, append(controllers, &openapiController{cfg: cfg}))
+}
+
+func TestOpenAPI_ServesYAML(t *testing.T) {
+	r := specRouter(OpenAPIConfig{Title: "T", Version: "1.0", SpecPath: "/openapi.json"}, DocController{})
+
+	rec := serve(r, http.MethodGet, "/api/openapi.yaml", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/yaml", rec.Header().Get("Content-Type"))
+
+	var spec map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec["openapi"])
+	// Version strings that look like numbers must stay strings.
+	assert.Equal(t, "1.0", spec["info"].(map[string]interface{})["version"])
+	assert.Contains(t, rec.Body.String(), "\"200\":")
+
+	// Content negotiation on the JSON path.
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	req.Header.Set("Accept", "application/yaml")
+	rec = serveRequest(r, req)
+	assert.Equal(t, "application/yaml", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "openapi: 3.0.3")
+
+	rec = serve(r, http.MethodGet, "/api/openapi.json", "", nil)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
+
+func TestOpenAPI_SpecCaching(t *testing.T) {
+	r := specRouter(OpenAPIConfig{Title: "T", Version: "1", SpecPath: "/openapi.json"}, DocController{})
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/api/openapi.json")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	modified := rec.Header().Get("Last-Modified")
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, modified)
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, http.StatusNotModified, get("/api/openapi.json", "If-None-Match", etag).Code)
+	assert.Equal(t, http.StatusNotModified, get("/api/openapi.json", "If-Modified-Since", modified).Code)
+	assert.NotEqual(t, etag, get("/api/openapi.yaml").Header().Get("ETag"))
+
+	rec = get("/api/openapi.json", "Accept-Encoding", "br, gzip")
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	zr, err := gzip.NewReader(rec.Body)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(zr)
+	assert.Equal(t, get("/api/openapi.json").Body.Bytes(), body)
+	assert.Empty(t, get("/api/openapi.json", "Accept-Encoding", "gzip;q=0").Header().Get("Content-Encoding"))
+
+	// A no-op invalidation keeps the validators.
+	r.InvalidateSpec()
+	assert.Equal(t, http.StatusNotModified, get("/api/openapi.json", "If-None-Match", etag).Code)
+
+	// Routes registered while serving show up in the spec and are routed.
+	r.NewRestRouter("/late").Get("/", func(ctx HTTPContext) { ctx.Response.WriteHeader(http.StatusTeapot) })
+	rec = get("/api/openapi.json", "If-None-Match", etag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"/late/"`)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	assert.Equal(t, http.StatusTeapot, get("/api/late/").Code)
+
+	// Changes the router can't see need an explicit invalidation.
+	etag = rec.Header().Get("ETag")
+	r.routes[len(r.routes)-1].ExcludeFromSpec()
+	assert.Equal(t, http.StatusNotModified, get("/api/openapi.json", "If-None-Match", etag).Code)
+	r.InvalidateSpec()
+	rec = get("/api/openapi.json", "If-None-Match", etag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `"/late/"`)
+}
+
+func TestRouter_ServeHTTP(t *testing.T) {
+	r := specRouter(OpenAPIConfig{Title: "T", Version: "1", SpecPath: "/openapi.json"}, DocController{})
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, "/api/openapi.json").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/api/missing").Code)
+	rec := serve(http.MethodGet, "/api//openapi.json")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/api/openapi.json", rec.Header().Get("Location"))
+
+	// Dispatch is mux's own: CurrentRoute, custom handlers and middleware apply.
+	r.MuxRouter.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	r.MuxRouter.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("X-Middleware", "1")
+			next.ServeHTTP(w, req)
+		})
+	})
+	assert.Equal(t, http.StatusGone, serve(http.MethodGet, "/api/missing").Code)
+
+	// Handlers run without the router's lock, so they can register routes.
+	r.NewRestRouter("/register").Get("/", func(ctx HTTPContext) {
+		assert.NotNil(t, mux.CurrentRoute(ctx.Request))
+		ctx.router.NewRestRouter("/registered").Get("/", func(ctx HTTPContext) {
+			ctx.SendStatus(http.StatusNoContent)
+		})
+		ctx.SendStatus(http.StatusCreated)
+	})
+	rec = serve(http.MethodGet, "/api/register/")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-Middleware"))
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodGet, "/api/registered/").Code)
+}
+
+type nullableSchema struct{}
+
+func (nullableSchema) OpenAPISchema() map[string]interface{} {
+	return map[string]interface{}{"type": "string", "nullable": true}
+}
+
+type nullableContainer struct {
+	Note  nullableSchema `json:"note"`
+	Child embedded       `json:"child"`
+}
+
+type nullableController struct{}
+
+func (nullableController) Load(router *Router) {
+	router.NewRestRouter("/nullable").Get("/", func(ctx HTTPContext) {}).Returns(200, nullableContainer{}, "OK")
+}
+
+func TestOpenAPI_31UsesTypeUnions(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{nullableController{}})
+
+	var spec map[string]interface{}
+	json.Unmarshal(buildDoc(OpenAPIConfig{Title: "T", Version: "1", OpenAPIVersion: OpenAPIVersion31}, router.routes), &spec)
+	assert.Equal(t, "3.1.0", spec["openapi"])
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	note := schemas["nullableContainer"].(map[string]interface{})["properties"].(map[string]interface{})["note"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"string", "null"}, note["type"])
+	assert.NotContains(t, note, "nullable")
+}
+
+func TestConvertSchemasTo31_WrapsUntypedNullable(t *testing.T) {
+	schema := map[string]interface{}{
+		"allOf":    []interface{}{map[string]interface{}{"$ref": "#/components/schemas/X"}},
+		"nullable": true,
+	}
+	convertSchemasTo31(schema)
+	assert.Equal(t, map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"allOf": []interface{}{map[string]interface{}{"$ref": "#/components/schemas/X"}}},
+			map[string]interface{}{"type": "null"},
+		},
+	}, schema)
+}
+
+func TestServer_GetOpenAPISpecYAML(t *testing.T) {
+	s := NewServer(DocController{})
+	assert.Nil(t, s.GetOpenAPISpecYAML())
+
+	s.UseOpenAPI(OpenAPIConfig{Title: "Widget API", Version: "1"})
+	out := string(s.GetOpenAPISpecYAML())
+	assert.Contains(t, out, "title: Widget API")
+	assert.Contains(t, out, "/widgets/{id}:")
+}
+
+type validatedFields struct {
+	Code    string            `json:"code" validate:"len=4,alphanum"`
+	Email   string            `json:"email" validate:"omitempty,email"`
+	Site    string            `json:"site" validate:"url"`
+	ID      string            `json:"id" validate:"uuid4"`
+	Addr    string            `json:"addr" validate:"ipv4"`
+	Labels  map[string]string `json:"labels" validate:"min=1,max=10"`
+	Ratio   float64           `json:"ratio" validate:"gt=0,lte=0.5"`
+	Level   int               `json:"level" validate:"oneof=1 2 3"`
+	Tags    []string          `json:"tags" validate:"required,unique,max=5,dive,min=2,hexadecimal"`
+	Comment string            `json:"comment" validate:"gt=3,lt=10"`
+}
+
+func TestApplyValidateTag_MapsValidatorRules(t *testing.T) {
+	db := newDocBuilder()
+	db.schemaFor(reflect.TypeOf(validatedFields{}))
+	props := db.schemas["validatedFields"]["properties"].(map[string]interface{})
+	prop := func(name string) map[string]interface{} { return props[name].(map[string]interface{}) }
+
+	assert.EqualValues(t, 4, prop("code")["minLength"])
+	assert.EqualValues(t, 4, prop("code")["maxLength"])
+	assert.Equal(t, "^[a-zA-Z0-9]+$", prop("code")["pattern"])
+	assert.Equal(t, "email", prop("email")["format"])
+	assert.Equal(t, "uri", prop("site")["format"])
+	assert.Equal(t, "uuid", prop("id")["format"])
+	assert.Equal(t, "ipv4", prop("addr")["format"])
+	assert.EqualValues(t, 1, prop("labels")["minProperties"])
+	assert.EqualValues(t, 10, prop("labels")["maxProperties"])
+
+	assert.EqualValues(t, 0, prop("ratio")["minimum"])
+	assert.Equal(t, true, prop("ratio")["exclusiveMinimum"])
+	assert.Equal(t, 0.5, prop("ratio")["maximum"])
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, prop("level")["enum"])
+
+	tags := prop("tags")
+	assert.Equal(t, true, tags["uniqueItems"])
+	assert.EqualValues(t, 5, tags["maxItems"])
+	items := tags["items"].(map[string]interface{})
+	assert.EqualValues(t, 2, items["minLength"])
+	assert.Equal(t, "^[0-9a-fA-F]+$", items["pattern"])
+	assert.NotContains(t, tags, "minItems")
+
+	assert.EqualValues(t, 4, prop("comment")["minLength"])
+	assert.EqualValues(t, 9, prop("comment")["maxLength"])
+	assert.Contains(t, db.schemas["validatedFields"]["required"], "tags")
+}
+
+func TestConvertSchemasTo31_ExclusiveBounds(t *testing.T) {
+	schema := map[string]interface{}{"minimum": 0, "exclusiveMinimum": true, "maximum": 5, "exclusiveMaximum": false}
+	convertSchemasTo31(schema)
+	assert.Equal(t, map[string]interface{}{"exclusiveMinimum": 0, "maximum": 5}, schema)
+
+	schema = map[string]interface{}{"type": "integer", "exclusiveMinimum": true}
+	convertSchemasTo31(schema)
+	assert.Equal(t, map[string]interface{}{"type": "integer"}, schema)
+}
+
+func TestConvertDocTo31_OnlyTouchesSchemas(t *testing.T) {
+	payload := map[string]interface{}{"nullable": true, "exclusiveMinimum": true}
+	doc := map[string]interface{}{
+		"paths": map[string]interface{}{"/x": map[string]interface{}{"get": map[string]interface{}{
+			"responses": map[string]interface{}{"200": map[string]interface{}{
+				"content": map[string]interface{}{"application/json": map[string]interface{}{
+					"schema":  map[string]interface{}{"type": "string", "nullable": true},
+					"example": payload,
+				}},
+			}},
+		}}},
+		"components": map[string]interface{}{"schemas": map[string]interface{}{
+			"X": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"n": map[string]interface{}{"type": "integer", "nullable": true}},
+				"example":    payload,
+			},
+		}},
+	}
+	convertDocTo31(doc)
+
+	media := doc["paths"].(map[string]interface{})["/x"].(map[string]interface{})["get"].(map[string]interface{})["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"string", "null"}, media["schema"].(map[string]interface{})["type"])
+	x := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})["X"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"integer", "null"}, x["properties"].(map[string]interface{})["n"].(map[string]interface{})["type"])
+	assert.Equal(t, map[string]interface{}{"nullable": true, "exclusiveMinimum": true}, payload)
+}
+
+func TestYAMLSpecPath(t *testing.T) {
+	assert.Equal(t, "/openapi.yaml", yamlSpecPath("/openapi.json"))
+	assert.Equal(t, "/spec.yaml", yamlSpecPath("/spec"))
+	assert.Equal(t, "/openapi.yaml", yamlSpecPath("/openapi.yaml"))
+	assert.Equal(t, "/openapi.yml", yamlSpecPath("/openapi.yml"))
+
+	r := specRouter(OpenAPIConfig{Title: "T", Version: "1", SpecPath: "/openapi.yaml"}, DocController{})
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.yaml", nil))
+	assert.Equal(t, "application/yaml", rec.Header().Get("Content-Type"))
+}
+
+func TestWantsYAML(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                     false,
+		"*/*":                  false,
+		"application/yaml":     true,
+		"text/yaml, */*;q=0.1": true,
+		"application/x-yaml;q=0.5, application/json": false,
+		"application/json;q=0.5, application/yaml":   true,
+		"application/vnd.oai.openapi+yaml":           true,
+		"application/yaml;q=0":                       false,
+		"text/x-yaml-ish":                            false,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		assert.Equal(t, want, wantsYAML(req), accept)
+	}
+}
+
+type fakeUUID [16]byte
+
+type UUID [16]byte
+
+type level int
+
+func (l level) MarshalText() ([]byte, error) { return []byte("info"), nil }
+
+type typedFields struct {
+	Small    int8          `json:"small"`
+	Count    int           `json:"count"`
+	Size     uint32        `json:"size"`
+	Max      uint64        `json:"max"`
+	Ratio    float32       `json:"ratio"`
+	Total    float64       `json:"total"`
+	BigID    int64         `json:"big_id,string"`
+	Flag     bool          `json:"flag,string"`
+	Timeout  time.Duration `json:"timeout"`
+	ID       UUID          `json:"id"`
+	Raw      fakeUUID      `json:"raw"`
+	Level    level         `json:"level"`
+	Note     *string       `json:"note"`
+	Child    *embedded     `json:"child"`
+	Required int           `json:"required" validate:"min=1"`
+}
+
+func TestSchemaFor_FormatsAndNullability(t *testing.T) {
+	db := newDocBuilder()
+	db.schemaFor(reflect.TypeOf(typedFields{}))
+	props := db.schemas["typedFields"]["properties"].(map[string]interface{})
+
+	assert.Equal(t, map[string]interface{}{"type": "integer", "format": "int32"}, props["small"])
+	assert.Equal(t, map[string]interface{}{"type": "integer", "format": "int64"}, props["count"])
+	assert.Equal(t, map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}, props["size"])
+	assert.Equal(t, map[string]interface{}{"type": "integer", "format": "uint64", "minimum": 0}, props["max"])
+	assert.Equal(t, map[string]interface{}{"type": "number", "format": "float"}, props["ratio"])
+	assert.Equal(t, map[string]interface{}{"type": "number", "format": "double"}, props["total"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "int64"}, props["big_id"])
+	assert.Equal(t, map[string]interface{}{"type": "string"}, props["flag"])
+	assert.Equal(t, map[string]interface{}{"type": "integer", "format": "int64", "description": "Duration in nanoseconds."}, props["timeout"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "uuid"}, props["id"])
+	assert.Equal(t, "byte", props["raw"].(map[string]interface{})["format"])
+	assert.Equal(t, map[string]interface{}{"type": "string"}, props["level"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "nullable": true}, props["note"])
+	assert.Equal(t, map[string]interface{}{
+		"allOf":    []interface{}{map[string]interface{}{"$ref": "#/components/schemas/embedded"}},
+		"nullable": true,
+	}, props["child"])
+	assert.EqualValues(t, 1, props["required"].(map[string]interface{})["minimum"])
+}
+
+type nullableBody struct {
+	Note *string `json:"note"`
+}
+
+type nullableBodyController struct{}
+
+func (nullableBodyController) Load(router *Router) {
+	router.NewRestRouter("/notes").Post("/", func(ctx HTTPContext) {}, nullableBody{})
+}
+
+func TestBuildInputSchema_UsesTypeUnionsForNullable(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{nullableBodyController{}})
+	schema := buildInputSchema(router.routes[0])
+
+	note := schema["$defs"].(map[string]interface{})["nullableBody"].(map[string]interface{})["properties"].(map[string]interface{})["note"]
+	assert.Equal(t, map[string]interface{}{"type": []interface{}{"string", "null"}}, note)
+}
+
+type headersController struct{}
+
+func (headersController) Load(router *Router) {
+	r := router.NewRestRouter("/sessions")
+	r.Post("/", func(ctx HTTPContext) {}, nil).
+		ReturnsHeader(201, "Location", "string", "URL of the session").
+		Returns(201, Widget{}, "Created").
+		ReturnsHeader(201, "Set-Cookie", "string", "Session cookie").
+		ReturnsHeader(429, "Retry-After", "integer", "Seconds to wait").
+		Cookie("session", "string", true, "Session id")
+}
+
+func TestOpenAPI_ResponseHeadersAndCookies(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{headersController{}})
+	var spec map[string]interface{}
+	json.Unmarshal(buildDoc(OpenAPIConfig{Title: "T", Version: "1"}, router.routes), &spec)
+	op := spec["paths"].(map[string]interface{})["/sessions/"].(map[string]interface{})["post"].(map[string]interface{})
+
+	responses := op["responses"].(map[string]interface{})
+	created := responses["201"].(map[string]interface{})
+	assert.Equal(t, "Created", created["description"])
+	headers := created["headers"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"description": "URL of the session", "schema": map[string]interface{}{"type": "string"}}, headers["Location"])
+	assert.Contains(t, headers, "Set-Cookie")
+
+	limited := responses["429"].(map[string]interface{})
+	assert.Equal(t, "Too Many Requests", limited["description"])
+	assert.Equal(t, "integer", limited["headers"].(map[string]interface{})["Retry-After"].(map[string]interface{})["schema"].(map[string]interface{})["type"])
+
+	cookie := op["parameters"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "cookie", cookie["in"])
+	assert.Equal(t, "session", cookie["name"])
+	assert.Equal(t, true, cookie["required"])
+}
+
+type operationIDController struct{}
+
+func (operationIDController) Load(router *Router) {
+	r := router.NewRestRouter("/ops")
+	r.Get("/a-b", func(ctx HTTPContext) {})
+	r.Get("/a_b", func(ctx HTTPContext) {})
+	r.Get("/{id}", func(ctx HTTPContext) {}).OperationID("get_ops_a_b")
+	r.Post("/", func(ctx HTTPContext) {}, nil).OperationID("createOp").MCPTool("make_op")
+}
+
+func TestOpenAPI_OperationIDs(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{operationIDController{}})
+	var spec map[string]interface{}
+	json.Unmarshal(buildDoc(OpenAPIConfig{Title: "T", Version: "1"}, router.routes), &spec)
+	paths := spec["paths"].(map[string]interface{})
+	opID := func(path, method string) interface{} {
+		return paths[path].(map[string]interface{})[method].(map[string]interface{})["operationId"]
+	}
+
+	// The explicit ID is claimed first; derived ones are deduplicated around it.
+	assert.Equal(t, "get_ops_a_b", opID("/ops/{id}", "get"))
+	assert.Equal(t, "get_ops_a_b_2", opID("/ops/a-b", "get"))
+	assert.Equal(t, "get_ops_a_b_3", opID("/ops/a_b", "get"))
+	assert.Equal(t, "createOp", opID("/ops/", "post"))
+}
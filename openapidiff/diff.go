@@ -0,0 +1,680 @@
+// Package openapidiff compares two OpenAPI 3 documents and classifies each
+// difference as breaking or non-breaking for existing clients.
+//
+// Typical use is gating CI: generate the current document with
+// requiem's Server.GetOpenAPISpec, compare it with a committed baseline, and
+// fail the build when Report.HasBreaking is true.
+package openapidiff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mborders/requiem/internal/specdoc"
+)
+
+// Severity says whether a change can break existing clients.
+type Severity string
+
+const (
+	Breaking    Severity = "breaking"
+	NonBreaking Severity = "non-breaking"
+)
+
+// Change is a single difference between two documents.
+type Change struct {
+	Severity Severity `json:"severity"`
+	// Operation is "METHOD /path", empty for document-wide changes.
+	Operation string `json:"operation,omitempty"`
+	// Location is where in the operation the change is, e.g. "query param
+	// limit" or "response 200 body.items[].name".
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (c Change) String() string {
+	s := strings.ToUpper(string(c.Severity)) + ": "
+	if c.Operation != "" {
+		s += c.Operation + ": "
+	}
+	if c.Location != "" {
+		s += c.Location + ": "
+	}
+	return s + c.Message
+}
+
+// Report lists the changes from a base document to a revision of it.
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// HasBreaking reports whether any change is breaking.
+func (r *Report) HasBreaking() bool {
+	return len(r.Breaking()) > 0
+}
+
+// Breaking returns the breaking changes.
+func (r *Report) Breaking() []Change {
+	var out []Change
+	for _, c := range r.Changes {
+		if c.Severity == Breaking {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+type obj = specdoc.Obj
+
+// Compare diffs two OpenAPI documents, given as JSON or YAML.
+func Compare(base, revision []byte) (*Report, error) {
+	b, err := specdoc.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("base document: %w", err)
+	}
+	r, err := specdoc.Parse(revision)
+	if err != nil {
+		return nil, fmt.Errorf("revised document: %w", err)
+	}
+
+	d := &differ{base: b, rev: r, seen: map[[2]string]bool{}}
+	d.paths()
+	sort.SliceStable(d.report.Changes, func(i, j int) bool {
+		a, b := d.report.Changes[i], d.report.Changes[j]
+		if a.Severity != b.Severity {
+			return a.Severity == Breaking
+		}
+		return a.Operation < b.Operation
+	})
+	return &d.report, nil
+}
+
+// direction is who reads a schema: the server (request bodies and params) or
+// the client (responses). It decides which way a change breaks.
+type direction int
+
+const (
+	request direction = iota
+	response
+)
+
+type differ struct {
+	base, rev obj
+	report    Report
+
+	// seen holds the $ref pairs already being compared, so recursive schemas
+	// terminate.
+	seen map[[2]string]bool
+}
+
+func (d *differ) add(sev Severity, op, loc, format string, args ...interface{}) {
+	d.report.Changes = append(d.report.Changes, Change{
+		Severity:  sev,
+		Operation: op,
+		Location:  loc,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+func (d *differ) paths() {
+	basePaths, _ := d.base["paths"].(obj)
+	revPaths, _ := d.rev["paths"].(obj)
+	// Paths are matched by their template, so renaming a path parameter
+	// doesn't read as the path being removed and another added.
+	baseByKey, revByKey := templateIndex(basePaths), templateIndex(revPaths)
+
+	for _, path := range specdoc.SortedKeys(basePaths) {
+		bItem, _ := basePaths[path].(obj)
+		rPath := revByKey[templateKey(path)]
+		rItem, _ := revPaths[rPath].(obj)
+		renames := d.pathRenames(path, rPath)
+		for _, m := range methods {
+			bOp, ok := bItem[m].(obj)
+			if !ok {
+				continue
+			}
+			op := strings.ToUpper(m) + " " + path
+			rOp, ok := rItem[m].(obj)
+			if !ok {
+				d.add(Breaking, op, "", "operation removed")
+				continue
+			}
+			d.operation(op, renames, bItem, bOp, rItem, rOp)
+		}
+	}
+	for _, path := range specdoc.SortedKeys(revPaths) {
+		rItem, _ := revPaths[path].(obj)
+		bItem, _ := basePaths[baseByKey[templateKey(path)]].(obj)
+		for _, m := range methods {
+			if _, ok := rItem[m].(obj); !ok {
+				continue
+			}
+			if _, ok := bItem[m].(obj); !ok {
+				d.add(NonBreaking, strings.ToUpper(m)+" "+path, "", "operation added")
+			}
+		}
+	}
+}
+
+func (d *differ) operation(op string, renames map[string]string, bItem, bOp, rItem, rOp obj) {
+	if !specdoc.Truthy(bOp["deprecated"]) && specdoc.Truthy(rOp["deprecated"]) {
+		d.add(NonBreaking, op, "", "operation deprecated")
+	}
+	d.params(op, collectParams(d.base, nil, bItem, bOp), collectParams(d.rev, renames, rItem, rOp))
+	d.requestBody(op, resolve(d.base, bOp["requestBody"]), resolve(d.rev, rOp["requestBody"]))
+	d.responses(op, specdoc.Object(bOp["responses"]), specdoc.Object(rOp["responses"]))
+}
+
+var templateParam = regexp.MustCompile(`\{[^}]*\}`)
+
+// templateKey identifies a path regardless of its parameter names:
+// /widgets/{id} and /widgets/{widgetId} are the same path.
+func templateKey(path string) string {
+	return templateParam.ReplaceAllString(path, "{}")
+}
+
+// templateIndex maps the template keys of paths to the paths.
+func templateIndex(paths obj) map[string]string {
+	out := map[string]string{}
+	for path := range paths {
+		out[templateKey(path)] = path
+	}
+	return out
+}
+
+// pathRenames returns the path parameters renamed between the base and
+// revised spelling of a path, revised name to base name, so their params
+// compare as the same param. Each rename is reported once per path.
+func (d *differ) pathRenames(base, rev string) map[string]string {
+	bNames, rNames := templateParam.FindAllString(base, -1), templateParam.FindAllString(rev, -1)
+	if len(bNames) != len(rNames) {
+		return nil
+	}
+	renames := map[string]string{}
+	for i, b := range bNames {
+		if b != rNames[i] {
+			renames[strings.Trim(rNames[i], "{}")] = strings.Trim(b, "{}")
+			d.add(NonBreaking, "", "path "+base, "path parameter %s renamed to %s", b, rNames[i])
+		}
+	}
+	return renames
+}
+
+// collectParams merges path-level and operation-level parameters, keyed by
+// "in name". Path params named in renames are keyed by their base name.
+func collectParams(doc obj, renames map[string]string, item, op obj) map[string]obj {
+	out := map[string]obj{}
+	for _, src := range []interface{}{item["parameters"], op["parameters"]} {
+		list, _ := src.([]interface{})
+		for _, p := range list {
+			po := resolve(doc, p)
+			if po == nil {
+				continue
+			}
+			name := specdoc.Str(po["name"])
+			if to, ok := renames[name]; ok && po["in"] == "path" {
+				name = to
+			}
+			out[fmt.Sprintf("%v param %v", po["in"], name)] = po
+		}
+	}
+	return out
+}
+
+func (d *differ) params(op string, base, rev map[string]obj) {
+	for _, key := range specdoc.SortedKeys(base) {
+		bp := base[key]
+		rp, ok := rev[key]
+		if !ok {
+			d.add(NonBreaking, op, key, "parameter removed")
+			continue
+		}
+		if !specdoc.Truthy(bp["required"]) && specdoc.Truthy(rp["required"]) {
+			d.add(Breaking, op, key, "parameter became required")
+		} else if specdoc.Truthy(bp["required"]) && !specdoc.Truthy(rp["required"]) {
+			d.add(NonBreaking, op, key, "parameter became optional")
+		}
+		d.schema(op, key, request, bp["schema"], rp["schema"])
+	}
+	for _, key := range specdoc.SortedKeys(rev) {
+		if _, ok := base[key]; ok {
+			continue
+		}
+		if specdoc.Truthy(rev[key]["required"]) {
+			d.add(Breaking, op, key, "required parameter added")
+		} else {
+			d.add(NonBreaking, op, key, "optional parameter added")
+		}
+	}
+}
+
+func (d *differ) requestBody(op string, base, rev obj) {
+	const loc = "request body"
+	switch {
+	case base == nil && rev == nil:
+		return
+	case base == nil:
+		if specdoc.Truthy(rev["required"]) {
+			d.add(Breaking, op, loc, "required request body added")
+		} else {
+			d.add(NonBreaking, op, loc, "optional request body added")
+		}
+		return
+	case rev == nil:
+		d.add(NonBreaking, op, loc, "request body removed")
+		return
+	}
+	if !specdoc.Truthy(base["required"]) && specdoc.Truthy(rev["required"]) {
+		d.add(Breaking, op, loc, "request body became required")
+	}
+	d.content(op, loc, request, specdoc.Object(base["content"]), specdoc.Object(rev["content"]))
+}
+
+func (d *differ) responses(op string, base, rev obj) {
+	for _, code := range specdoc.SortedKeys(base) {
+		loc := "response " + code
+		bResp := resolve(d.base, base[code])
+		rRaw, ok := rev[code]
+		if !ok {
+			if strings.HasPrefix(code, "2") {
+				d.add(Breaking, op, loc, "success response removed")
+			} else {
+				d.add(NonBreaking, op, loc, "response removed")
+			}
+			continue
+		}
+		rResp := resolve(d.rev, rRaw)
+		d.content(op, loc, response, specdoc.Object(bResp["content"]), specdoc.Object(rResp["content"]))
+
+		bHeaders, rHeaders := specdoc.Object(bResp["headers"]), specdoc.Object(rResp["headers"])
+		for _, h := range specdoc.SortedKeys(bHeaders) {
+			if _, ok := rHeaders[h]; !ok {
+				d.add(Breaking, op, loc+" header "+h, "response header removed")
+			}
+		}
+	}
+	for _, code := range specdoc.SortedKeys(rev) {
+		if _, ok := base[code]; !ok {
+			d.add(NonBreaking, op, "response "+code, "response added")
+		}
+	}
+}
+
+func (d *differ) content(op, loc string, dir direction, base, rev obj) {
+	for _, mt := range specdoc.SortedKeys(base) {
+		rMedia, ok := rev[mt]
+		if !ok {
+			d.add(Breaking, op, loc, "media type %s removed", mt)
+			continue
+		}
+		d.schema(op, loc+" body", dir, specdoc.Object(base[mt])["schema"], specdoc.Object(rMedia)["schema"])
+	}
+	for _, mt := range specdoc.SortedKeys(rev) {
+		if _, ok := base[mt]; !ok {
+			d.add(NonBreaking, op, loc, "media type %s added", mt)
+		}
+	}
+}
+
+// schema compares two schemas read in the given direction. Requests break
+// when the server accepts less than before; responses break when the client
+// may receive something it didn't before.
+func (d *differ) schema(op, loc string, dir direction, baseRaw, revRaw interface{}) {
+	base, rev := specdoc.Object(baseRaw), specdoc.Object(revRaw)
+	if base == nil || rev == nil {
+		return
+	}
+	bRef, rRef := schemaRef(base), schemaRef(rev)
+	if bRef != "" || rRef != "" {
+		key := [2]string{bRef, rRef + fmt.Sprint(dir)}
+		if d.seen[key] {
+			return
+		}
+		d.seen[key] = true
+		defer delete(d.seen, key)
+	}
+	base, rev = flatten(d.base, base), flatten(d.rev, rev)
+
+	bType, rType := specdoc.SchemaType(base), specdoc.SchemaType(rev)
+	if bType != "" && rType != "" && bType != rType {
+		d.add(Breaking, op, loc, "type changed from %s to %s", bType, rType)
+		return
+	}
+	if bf, rf := specdoc.Str(base["format"]), specdoc.Str(rev["format"]); bf != rf && (bf != "" || rf != "") {
+		d.add(Breaking, op, loc, "format changed from %q to %q", bf, rf)
+	}
+
+	bNull, rNull := specdoc.Nullable(base), specdoc.Nullable(rev)
+	if bNull != rNull {
+		narrowed := bNull && !rNull
+		if (dir == request) == narrowed {
+			d.add(Breaking, op, loc, "nullable changed from %v to %v", bNull, rNull)
+		} else {
+			d.add(NonBreaking, op, loc, "nullable changed from %v to %v", bNull, rNull)
+		}
+	}
+
+	d.enum(op, loc, dir, base["enum"], rev["enum"])
+	d.bounds(op, loc, dir, base, rev)
+	d.variants(op, loc, dir, base, rev)
+
+	d.schema(op, loc+"[]", dir, base["items"], rev["items"])
+	d.schema(op, loc+"{}", dir, base["additionalProperties"], rev["additionalProperties"])
+	d.properties(op, loc, dir, base, rev)
+}
+
+func (d *differ) properties(op, loc string, dir direction, base, rev obj) {
+	bProps, rProps := specdoc.Object(base["properties"]), specdoc.Object(rev["properties"])
+	bReq, rReq := stringSet(base["required"]), stringSet(rev["required"])
+
+	for _, name := range specdoc.SortedKeys(bProps) {
+		ploc := loc + "." + name
+		rp, ok := rProps[name]
+		if !ok {
+			if dir == response {
+				d.add(Breaking, op, ploc, "property removed")
+			} else {
+				d.add(NonBreaking, op, ploc, "property removed")
+			}
+			continue
+		}
+		switch {
+		case !bReq[name] && rReq[name] && dir == request:
+			d.add(Breaking, op, ploc, "property became required")
+		case bReq[name] && !rReq[name] && dir == response:
+			d.add(Breaking, op, ploc, "property is no longer always present")
+		case bReq[name] != rReq[name]:
+			d.add(NonBreaking, op, ploc, "required changed from %v to %v", bReq[name], rReq[name])
+		}
+		d.schema(op, ploc, dir, bProps[name], rp)
+	}
+	for _, name := range specdoc.SortedKeys(rProps) {
+		if _, ok := bProps[name]; ok {
+			continue
+		}
+		if dir == request && rReq[name] {
+			d.add(Breaking, op, loc+"."+name, "required property added")
+		} else {
+			d.add(NonBreaking, op, loc+"."+name, "property added")
+		}
+	}
+}
+
+func (d *differ) enum(op, loc string, dir direction, base, rev interface{}) {
+	bList, bOK := base.([]interface{})
+	rList, rOK := rev.([]interface{})
+	switch {
+	case !bOK && !rOK:
+		return
+	case !bOK:
+		if dir == request {
+			d.add(Breaking, op, loc, "values restricted to an enum")
+		} else {
+			d.add(NonBreaking, op, loc, "values restricted to an enum")
+		}
+		return
+	case !rOK:
+		if dir == response {
+			d.add(Breaking, op, loc, "enum restriction removed")
+		} else {
+			d.add(NonBreaking, op, loc, "enum restriction removed")
+		}
+		return
+	}
+
+	bSet, rSet := valueSet(bList), valueSet(rList)
+	var removed, added []string
+	for v := range bSet {
+		if !rSet[v] {
+			removed = append(removed, v)
+		}
+	}
+	for v := range rSet {
+		if !bSet[v] {
+			added = append(added, v)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+	if len(removed) > 0 {
+		sev := NonBreaking
+		if dir == request {
+			sev = Breaking
+		}
+		d.add(sev, op, loc, "enum values removed: %s", strings.Join(removed, ", "))
+	}
+	if len(added) > 0 {
+		sev := NonBreaking
+		if dir == response {
+			sev = Breaking
+		}
+		d.add(sev, op, loc, "enum values added: %s", strings.Join(added, ", "))
+	}
+}
+
+// bounds compares numeric constraints. lower are minimums, where raising the
+// value narrows what is allowed; the others are maximums. minimum and maximum
+// are compared together with their exclusive forms.
+func (d *differ) bounds(op, loc string, dir direction, base, rev obj) {
+	lower := []string{"minimum", "minLength", "minItems", "minProperties"}
+	upper := []string{"maximum", "maxLength", "maxItems", "maxProperties"}
+
+	check := func(key string, isLower bool) {
+		bb, rb := boundOf(base, key, isLower), boundOf(rev, key, isLower)
+		if bb == rb {
+			return
+		}
+		var narrowed bool
+		switch {
+		case !bb.ok:
+			narrowed = true
+		case !rb.ok:
+			narrowed = false
+		case bb.value == rb.value:
+			narrowed = rb.exclusive
+		case isLower:
+			narrowed = rb.value > bb.value
+		default:
+			narrowed = rb.value < bb.value
+		}
+		sev := NonBreaking
+		if narrowed == (dir == request) {
+			sev = Breaking
+		}
+		d.add(sev, op, loc, "%s changed from %s to %s", key, bb, rb)
+	}
+	for _, k := range lower {
+		check(k, true)
+	}
+	for _, k := range upper {
+		check(k, false)
+	}
+}
+
+// bound is a schema's effective value for a numeric constraint.
+type bound struct {
+	value     float64
+	exclusive bool
+	ok        bool
+}
+
+func (b bound) String() string {
+	switch {
+	case !b.ok:
+		return "none"
+	case b.exclusive:
+		return fmt.Sprintf("%v (exclusive)", b.value)
+	}
+	return fmt.Sprint(b.value)
+}
+
+// boundOf reads a constraint. For minimum and maximum it folds in the
+// exclusive forms: the 3.0 boolean exclusiveMinimum modifying minimum, and
+// the 3.1 numeric one, taking the tighter bound when both are set.
+func boundOf(s obj, key string, isLower bool) bound {
+	var b bound
+	b.value, b.ok = number(s[key])
+	if key != "minimum" && key != "maximum" {
+		return b
+	}
+	exclusiveKey := "exclusiveM" + strings.TrimPrefix(key, "m")
+	switch v := s[exclusiveKey].(type) {
+	case bool:
+		b.exclusive = v && b.ok
+	default:
+		n, ok := number(v)
+		if !ok {
+			break
+		}
+		tighter := n > b.value
+		if !isLower {
+			tighter = n < b.value
+		}
+		if !b.ok || tighter || n == b.value {
+			b = bound{value: n, exclusive: true, ok: true}
+		}
+	}
+	return b
+}
+
+// variants compares oneOf/anyOf alternatives by their $ref names.
+func (d *differ) variants(op, loc string, dir direction, base, rev obj) {
+	for _, key := range []string{"oneOf", "anyOf"} {
+		bList, bOK := base[key].([]interface{})
+		rList, rOK := rev[key].([]interface{})
+		if !bOK || !rOK {
+			continue
+		}
+		bSet, rSet := refSet(bList), refSet(rList)
+		for _, name := range specdoc.SortedKeys(bSet) {
+			if !rSet[name] {
+				sev := NonBreaking
+				if dir == request {
+					sev = Breaking
+				}
+				d.add(sev, op, loc, "%s variant %s removed", key, name)
+			}
+		}
+		for _, name := range specdoc.SortedKeys(rSet) {
+			if !bSet[name] {
+				sev := NonBreaking
+				if dir == response {
+					sev = Breaking
+				}
+				d.add(sev, op, loc, "%s variant %s added", key, name)
+			}
+		}
+	}
+}
+
+// resolve follows a local $ref ("#/components/...") to the object it names.
+func resolve(doc obj, v interface{}) obj {
+	o, ok := v.(obj)
+	for i := 0; ok && i < 32; i++ {
+		ref, isRef := o["$ref"].(string)
+		if !isRef {
+			return o
+		}
+		o, ok = lookup(doc, ref)
+	}
+	return o
+}
+
+// flatten resolves a schema that wraps a single $ref in allOf (how nullable
+// references are written) into the referenced schema, keeping nullable.
+func flatten(doc, s obj) obj {
+	s = resolve(doc, s)
+	all, ok := s["allOf"].([]interface{})
+	if !ok || len(all) != 1 || s["type"] != nil {
+		return s
+	}
+	inner := resolve(doc, all[0])
+	if inner == nil {
+		return s
+	}
+	out := obj{}
+	for k, v := range inner {
+		out[k] = v
+	}
+	if specdoc.Nullable(s) {
+		out["nullable"] = true
+	}
+	return out
+}
+
+func lookup(doc obj, ref string) (obj, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var cur interface{} = doc
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(obj)
+		if !ok {
+			return nil, false
+		}
+		cur = m[part]
+	}
+	o, ok := cur.(obj)
+	return o, ok
+}
+
+func refName(s obj) string {
+	ref, _ := s["$ref"].(string)
+	return ref
+}
+
+// schemaRef returns the schema's $ref, looking through a single-element allOf.
+func schemaRef(s obj) string {
+	if all, ok := s["allOf"].([]interface{}); ok && len(all) == 1 {
+		return refName(specdoc.Object(all[0]))
+	}
+	return refName(s)
+}
+
+func refSet(list []interface{}) map[string]bool {
+	out := map[string]bool{}
+	for _, v := range list {
+		if o, ok := v.(obj); ok {
+			if ref := refName(o); ref != "" {
+				out[ref[strings.LastIndex(ref, "/")+1:]] = true
+			}
+		}
+	}
+	return out
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func stringSet(v interface{}) map[string]bool {
+	out := map[string]bool{}
+	list, _ := v.([]interface{})
+	for _, s := range list {
+		out[fmt.Sprint(s)] = true
+	}
+	return out
+}
+
+func valueSet(list []interface{}) map[string]bool {
+	out := map[string]bool{}
+	for _, v := range list {
+		out[fmt.Sprint(v)] = true
+	}
+	return out
+}
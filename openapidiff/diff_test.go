@@ -0,0 +1,200 @@
+package openapidiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const baseDoc = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/widgets": {
+      "get": {
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer"}},
+          {"name": "sort", "in": "query", "schema": {"type": "string", "enum": ["name", "created"]}}
+        ],
+        "responses": {
+          "200": {"description": "OK", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Widget"}}}}},
+          "404": {"description": "Not found"}
+        }
+      },
+      "post": {
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/NewWidget"}}}},
+        "responses": {"201": {"description": "Created", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Widget"}}}}}
+      }
+    },
+    "/widgets/{id}": {
+      "delete": {
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"204": {"description": "Deleted"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Widget": {
+        "type": "object",
+        "required": ["id", "name"],
+        "properties": {
+          "id": {"type": "string"},
+          "name": {"type": "string"},
+          "color": {"type": "string", "enum": ["red", "blue"]},
+          "parent": {"allOf": [{"$ref": "#/components/schemas/Widget"}], "nullable": true}
+        }
+      },
+      "NewWidget": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string", "maxLength": 50},
+          "color": {"type": "string"}
+        }
+      }
+    }
+  }
+}`
+
+func TestCompare_Identical(t *testing.T) {
+	report, err := Compare([]byte(baseDoc), []byte(baseDoc))
+	assert.NoError(t, err)
+	assert.Empty(t, report.Changes)
+	assert.False(t, report.HasBreaking())
+}
+
+const revisedDoc = `
+openapi: 3.1.0
+paths:
+  /widgets:
+    get:
+      parameters:
+        - {name: limit, in: query, schema: {type: string}}
+        - {name: sort, in: query, schema: {type: string, enum: [name, created, updated]}}
+        - {name: tenant, in: header, required: true, schema: {type: string}}
+      responses:
+        200:
+          description: OK
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: "#/components/schemas/Widget"}}
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/NewWidget"}
+      responses:
+        201:
+          description: Created
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Widget"}
+  /gadgets:
+    get:
+      responses:
+        200: {description: OK}
+components:
+  schemas:
+    Widget:
+      type: object
+      required: [id]
+      properties:
+        id: {type: string}
+        name: {type: string}
+        color: {type: string, enum: [red, blue, green]}
+        parent: {allOf: [{$ref: "#/components/schemas/Widget"}], nullable: true}
+        size: {type: integer}
+    NewWidget:
+      type: object
+      required: [name, size]
+      properties:
+        name: {type: string, maxLength: 20}
+        size: {type: integer}
+`
+
+func TestCompare_Classifies(t *testing.T) {
+	report, err := Compare([]byte(baseDoc), []byte(revisedDoc))
+	assert.NoError(t, err)
+	assert.True(t, report.HasBreaking())
+
+	var lines []string
+	for _, c := range report.Changes {
+		lines = append(lines, c.String())
+	}
+	assert.Equal(t, []string{
+		"BREAKING: DELETE /widgets/{id}: operation removed",
+		"BREAKING: GET /widgets: query param limit: type changed from integer to string",
+		"BREAKING: GET /widgets: header param tenant: required parameter added",
+		"BREAKING: GET /widgets: response 200 body[].color: enum values added: green",
+		"BREAKING: GET /widgets: response 200 body[].name: property is no longer always present",
+		"BREAKING: POST /widgets: request body body.name: maxLength changed from 50 to 20",
+		"BREAKING: POST /widgets: request body body.size: required property added",
+		"BREAKING: POST /widgets: response 201 body.color: enum values added: green",
+		"BREAKING: POST /widgets: response 201 body.name: property is no longer always present",
+		"NON-BREAKING: GET /gadgets: operation added",
+		"NON-BREAKING: GET /widgets: query param sort: enum values added: updated",
+		"NON-BREAKING: GET /widgets: response 200 body[].size: property added",
+		"NON-BREAKING: GET /widgets: response 404: response removed",
+		"NON-BREAKING: POST /widgets: request body body.color: property removed",
+		"NON-BREAKING: POST /widgets: response 201 body.size: property added",
+	}, lines)
+}
+
+func TestCompare_ResponseWidening(t *testing.T) {
+	base := `{"openapi": "3.0.3", "paths": {"/x": {"get": {"responses": {"200": {"description": "OK",
+		"content": {"application/json": {"schema": {"type": "object", "properties": {"n": {"type": "integer", "maximum": 10}}}}}}}}}}}`
+	revised := `{"openapi": "3.0.3", "paths": {"/x": {"get": {"responses": {"200": {"description": "OK",
+		"content": {"application/json": {"schema": {"type": "object", "properties": {"n": {"type": "integer", "nullable": true}}}}}}}}}}}`
+
+	report, err := Compare([]byte(base), []byte(revised))
+	assert.NoError(t, err)
+	assert.Len(t, report.Breaking(), 2)
+	assert.Equal(t, "BREAKING: GET /x: response 200 body.n: nullable changed from false to true", report.Breaking()[0].String())
+	assert.Equal(t, "maximum changed from 10 to none", report.Breaking()[1].Message)
+}
+
+func TestCompare_InvalidDocument(t *testing.T) {
+	_, err := Compare([]byte(`{"swagger": "2.0"}`), []byte(baseDoc))
+	assert.EqualError(t, err, "base document: not an OpenAPI document")
+}
+
+func TestCompare_PathParamRenamed(t *testing.T) {
+	base := `{"openapi": "3.0.3", "paths": {"/widgets/{id}": {"get": {
+		"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+		"responses": {"200": {"description": "OK"}}}}}}`
+	revised := `{"openapi": "3.0.3", "paths": {"/widgets/{widgetId}": {"get": {
+		"parameters": [{"name": "widgetId", "in": "path", "required": true, "schema": {"type": "string"}}],
+		"responses": {"200": {"description": "OK"}}}}}}`
+
+	report, err := Compare([]byte(base), []byte(revised))
+	assert.NoError(t, err)
+	assert.False(t, report.HasBreaking())
+	assert.Len(t, report.Changes, 1)
+	assert.Equal(t, "NON-BREAKING: path /widgets/{id}: path parameter {id} renamed to {widgetId}", report.Changes[0].String())
+}
+
+func TestCompare_ExclusiveBounds(t *testing.T) {
+	doc := func(version, schema string) []byte {
+		return []byte(`{"openapi": "` + version + `", "paths": {"/x": {"post": {"requestBody": {"content": {"application/json": {"schema": ` +
+			schema + `}}}, "responses": {"204": {"description": "OK"}}}}}}`)
+	}
+
+	// 3.0 boolean exclusiveMinimum tightening the same minimum.
+	report, err := Compare(doc("3.0.3", `{"type": "integer", "minimum": 1}`), doc("3.0.3", `{"type": "integer", "minimum": 1, "exclusiveMinimum": true}`))
+	assert.NoError(t, err)
+	if assert.Len(t, report.Breaking(), 1) {
+		assert.Equal(t, "minimum changed from 1 to 1 (exclusive)", report.Breaking()[0].Message)
+	}
+
+	// Loosening is not breaking for a request.
+	report, err = Compare(doc("3.0.3", `{"type": "integer", "maximum": 9, "exclusiveMaximum": true}`), doc("3.0.3", `{"type": "integer", "maximum": 9}`))
+	assert.NoError(t, err)
+	assert.False(t, report.HasBreaking())
+	assert.Len(t, report.Changes, 1)
+
+	// The same bound spelled the 3.0 and the 3.1 way is no change.
+	report, err = Compare(doc("3.0.3", `{"type": "integer", "minimum": 1, "exclusiveMinimum": true}`), doc("3.1.0", `{"type": "integer", "exclusiveMinimum": 1}`))
+	assert.NoError(t, err)
+	assert.Empty(t, report.Changes)
+}
@@ -0,0 +1,411 @@
+// Package openapimerge combines the OpenAPI documents of several services
+// into one, e.g. for a portal in front of an API gateway.
+//
+// Paths, webhooks, tags and components are merged. Components whose names
+// clash with a different definition from another service are renamed with
+// the service's name as a prefix, and every reference to them is rewritten.
+// Each service's paths can be mounted under a gateway prefix; otherwise its
+// servers are kept on its operations so they still resolve to the service.
+// A tag defined by several services keeps one entry, with their
+// descriptions combined.
+package openapimerge
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/mborders/requiem/internal/specdoc"
+)
+
+// Service is one document to merge.
+type Service struct {
+	// Name identifies the service. Renamed components are prefixed with it,
+	// e.g. billing's Invoice schema becomes BillingInvoice.
+	Name string
+	// Spec is the service's document as JSON or YAML, e.g. from
+	// requiem's Server.GetOpenAPISpec.
+	Spec []byte
+	// PathPrefix is prepended to every path, for services mounted under a
+	// gateway route such as "/billing". When empty, paths are kept and the
+	// service's servers are set on each of its operations.
+	PathPrefix string
+}
+
+// Options configures the combined document.
+type Options struct {
+	Title       string
+	Version     string
+	Description string
+	// Servers are the combined document's server URLs, typically the gateway.
+	Servers []string
+	// Namespace prefixes every component with its service's name, not only
+	// the clashing ones, so names don't change as services are added.
+	Namespace bool
+}
+
+// Merge combines the services' documents, in order, into one JSON document.
+// Two services defining the same operation or webhook is an error, as is
+// mixing OpenAPI 3.0 and 3.1 documents.
+func Merge(services []Service, opts Options) ([]byte, error) {
+	m := &merger{
+		opts:       opts,
+		paths:      obj{},
+		webhooks:   obj{},
+		components: map[string]obj{},
+		owners:     map[string]string{},
+		opIDs:      map[string]bool{},
+	}
+	for _, svc := range services {
+		doc, err := specdoc.Parse(svc.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", svc.Name, err)
+		}
+		if err := m.add(svc, doc); err != nil {
+			return nil, err
+		}
+	}
+	return json.MarshalIndent(m.document(), "", "  ")
+}
+
+type obj = specdoc.Obj
+
+type merger struct {
+	opts       Options
+	version    string
+	paths      obj
+	webhooks   obj
+	webhookKey string
+	tags       []interface{}
+	tagIndex   map[string]obj
+	components map[string]obj
+	// owners maps "METHOD path" and webhook names to the service that
+	// defined them, for conflict errors.
+	owners map[string]string
+	opIDs  map[string]bool
+}
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+func (m *merger) add(svc Service, doc obj) error {
+	version, _ := doc["openapi"].(string)
+	if m.version == "" {
+		m.version = version
+	} else if minorVersion(version) != minorVersion(m.version) {
+		return fmt.Errorf("%s: OpenAPI %s cannot be merged with %s", svc.Name, version, m.version)
+	}
+
+	m.renameComponents(svc, doc)
+
+	prefix := strings.TrimRight(svc.PathPrefix, "/")
+	servers, _ := doc["servers"].([]interface{})
+	security, hasSecurity := doc["security"]
+	paths, _ := doc["paths"].(obj)
+	for _, path := range specdoc.SortedKeys(paths) {
+		item, _ := paths[path].(obj)
+		target := prefix + path
+		merged, _ := m.paths[target].(obj)
+		if merged == nil {
+			merged = obj{}
+			m.paths[target] = merged
+		}
+		for _, key := range specdoc.SortedKeys(item) {
+			if !isMethod(key) {
+				// The path item may be shared with other services, so its
+				// parameters and servers are pushed down to the operations.
+				continue
+			}
+			op, _ := item[key].(obj)
+			owner := strings.ToUpper(key) + " " + target
+			if other, ok := m.owners[owner]; ok {
+				return fmt.Errorf("%s: %s is also defined by %s", svc.Name, owner, other)
+			}
+			m.owners[owner] = svc.Name
+			m.operation(svc, op, item, servers, security, hasSecurity)
+			merged[key] = op
+		}
+	}
+
+	key, hooks := "webhooks", doc["webhooks"]
+	if hooks == nil {
+		key, hooks = "x-webhooks", doc["x-webhooks"]
+	}
+	hookMap, _ := hooks.(obj)
+	for _, name := range specdoc.SortedKeys(hookMap) {
+		if other, ok := m.owners["webhook "+name]; ok {
+			return fmt.Errorf("%s: webhook %q is also defined by %s", svc.Name, name, other)
+		}
+		m.owners["webhook "+name] = svc.Name
+		m.webhooks[name] = hookMap[name]
+		m.webhookKey = key
+	}
+
+	tags, _ := doc["tags"].([]interface{})
+	for _, t := range tags {
+		tag, _ := t.(obj)
+		name, _ := tag["name"].(string)
+		if name == "" {
+			continue
+		}
+		if merged, ok := m.tagIndex[name]; ok {
+			mergeTag(merged, tag)
+			continue
+		}
+		if m.tagIndex == nil {
+			m.tagIndex = map[string]obj{}
+		}
+		m.tagIndex[name] = tag
+		m.tags = append(m.tags, tag)
+	}
+	return nil
+}
+
+// operation makes op self-contained: it takes over its path item's shared
+// parameters, the document-wide security and servers, and a unique
+// operationId.
+func (m *merger) operation(svc Service, op, item obj, servers []interface{}, security interface{}, hasSecurity bool) {
+	if shared, ok := item["parameters"].([]interface{}); ok {
+		own, _ := op["parameters"].([]interface{})
+		for _, p := range shared {
+			if !hasParam(own, p) {
+				own = append(own, p)
+			}
+		}
+		op["parameters"] = own
+	}
+	if _, ok := op["security"]; !ok && hasSecurity {
+		op["security"] = security
+	}
+	if _, ok := op["servers"]; !ok && svc.PathPrefix == "" {
+		if own, ok := item["servers"].([]interface{}); ok {
+			op["servers"] = own
+		} else if len(servers) > 0 {
+			op["servers"] = servers
+		}
+	}
+	if id, ok := op["operationId"].(string); ok {
+		if m.opIDs[id] {
+			prefixed := snakeName(svc.Name) + "_" + id
+			id = prefixed
+			for n := 2; m.opIDs[id]; n++ {
+				id = fmt.Sprintf("%s%d", prefixed, n)
+			}
+			op["operationId"] = id
+		}
+		m.opIDs[id] = true
+	}
+}
+
+// hasParam reports whether params has one with p's name and location.
+func hasParam(params []interface{}, p interface{}) bool {
+	po, _ := p.(obj)
+	for _, q := range params {
+		qo, _ := q.(obj)
+		if qo["name"] == po["name"] && qo["in"] == po["in"] {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTag folds a later service's definition of a tag into the merged one:
+// fields it lacks are taken over, and a different description is appended
+// as its own paragraph so neither service's text is lost.
+func mergeTag(merged, tag obj) {
+	for key, v := range tag {
+		if _, ok := merged[key]; !ok {
+			merged[key] = v
+		}
+	}
+	own, _ := merged["description"].(string)
+	other, _ := tag["description"].(string)
+	if other != "" && own != other && !strings.Contains(own, other) {
+		merged["description"] = own + "\n\n" + other
+	}
+}
+
+// renameComponents moves the document's components into the merged set,
+// renaming those that clash (or all, with Options.Namespace) and rewriting
+// the references to them throughout doc.
+func (m *merger) renameComponents(svc Service, doc obj) {
+	components, _ := doc["components"].(obj)
+	refs := map[string]string{}
+	schemes := map[string]string{}
+	for _, kind := range specdoc.SortedKeys(components) {
+		defs, _ := components[kind].(obj)
+		if m.components[kind] == nil {
+			m.components[kind] = obj{}
+		}
+		merged := m.components[kind]
+		for _, name := range specdoc.SortedKeys(defs) {
+			clashes := func(target string) bool {
+				return merged[target] != nil && !reflect.DeepEqual(merged[target], defs[name]) ||
+					target != name && defs[target] != nil
+			}
+			target := name
+			if m.opts.Namespace || clashes(target) {
+				target = specdoc.ExportName(svc.Name) + name
+			}
+			for n := 2; clashes(target); n++ {
+				target = fmt.Sprintf("%s%s%d", specdoc.ExportName(svc.Name), name, n)
+			}
+			if target != name {
+				refs["#/components/"+kind+"/"+name] = "#/components/" + kind + "/" + target
+				if kind == "securitySchemes" {
+					schemes[name] = target
+				}
+			}
+		}
+	}
+	// Rewrite before copying so renamed definitions referring to each other
+	// are consistent too.
+	rewriteRefs(doc, refs)
+	rewriteSecurity(doc, schemes)
+	for _, kind := range specdoc.SortedKeys(components) {
+		defs, _ := components[kind].(obj)
+		for _, name := range specdoc.SortedKeys(defs) {
+			target := name
+			if to, ok := refs["#/components/"+kind+"/"+name]; ok {
+				target = strings.TrimPrefix(to, "#/components/"+kind+"/")
+			}
+			m.components[kind][target] = defs[name]
+		}
+	}
+}
+
+// rewriteRefs replaces renamed component references: $ref values and
+// discriminator mappings.
+func rewriteRefs(v interface{}, refs map[string]string) {
+	if len(refs) == 0 {
+		return
+	}
+	rename := func(s string) string {
+		for from, to := range refs {
+			if s == from || strings.HasPrefix(s, from+"/") {
+				return to + strings.TrimPrefix(s, from)
+			}
+		}
+		return s
+	}
+	switch t := v.(type) {
+	case obj:
+		for k, e := range t {
+			if s, ok := e.(string); ok && k == "$ref" {
+				t[k] = rename(s)
+				continue
+			}
+			if k == "mapping" {
+				if mapping, ok := e.(obj); ok {
+					for mk, mv := range mapping {
+						if s, ok := mv.(string); ok {
+							mapping[mk] = rename(s)
+						}
+					}
+					continue
+				}
+			}
+			rewriteRefs(e, refs)
+		}
+	case []interface{}:
+		for _, e := range t {
+			rewriteRefs(e, refs)
+		}
+	}
+}
+
+// rewriteSecurity renames security schemes in the document's and every
+// operation's security requirements, which name schemes rather than $ref them.
+func rewriteSecurity(doc obj, schemes map[string]string) {
+	if len(schemes) == 0 {
+		return
+	}
+	rename := func(v interface{}) {
+		reqs, _ := v.([]interface{})
+		for i, r := range reqs {
+			req, _ := r.(obj)
+			out := obj{}
+			for name, scopes := range req {
+				if to, ok := schemes[name]; ok {
+					name = to
+				}
+				out[name] = scopes
+			}
+			reqs[i] = out
+		}
+	}
+	rename(doc["security"])
+	paths, _ := doc["paths"].(obj)
+	for _, item := range paths {
+		for key, op := range item.(obj) {
+			if o, ok := op.(obj); ok && isMethod(key) {
+				rename(o["security"])
+			}
+		}
+	}
+}
+
+func (m *merger) document() obj {
+	info := obj{"title": m.opts.Title, "version": m.opts.Version}
+	if m.opts.Description != "" {
+		info["description"] = m.opts.Description
+	}
+	version := m.version
+	if version == "" {
+		version = "3.0.3"
+	}
+	doc := obj{"openapi": version, "info": info, "paths": m.paths}
+	if len(m.opts.Servers) > 0 {
+		servers := make([]interface{}, 0, len(m.opts.Servers))
+		for _, u := range m.opts.Servers {
+			servers = append(servers, obj{"url": u})
+		}
+		doc["servers"] = servers
+	}
+	if len(m.webhooks) > 0 {
+		key := m.webhookKey
+		if strings.HasPrefix(version, "3.1") {
+			key = "webhooks"
+		}
+		doc[key] = m.webhooks
+	}
+	if len(m.tags) > 0 {
+		doc["tags"] = m.tags
+	}
+	components := obj{}
+	for kind, defs := range m.components {
+		if len(defs) > 0 {
+			components[kind] = defs
+		}
+	}
+	if len(components) > 0 {
+		doc["components"] = components
+	}
+	return doc
+}
+
+// minorVersion returns the major.minor part of an OpenAPI version.
+func minorVersion(v string) string {
+	if parts := strings.SplitN(v, ".", 3); len(parts) >= 2 {
+		return parts[0] + "." + parts[1]
+	}
+	return v
+}
+
+func isMethod(key string) bool {
+	for _, m := range httpMethods {
+		if key == m {
+			return true
+		}
+	}
+	return false
+}
+
+// snakeName turns a service name like "billing-api" into "billing_api" for
+// operationId prefixes.
+func snakeName(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "_")
+}
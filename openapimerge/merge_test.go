@@ -0,0 +1,181 @@
+package openapimerge
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mborders/requiem"
+	"github.com/stretchr/testify/assert"
+)
+
+type Item struct {
+	ID    string `json:"id"`
+	Price int    `json:"price"`
+}
+
+type Problem struct {
+	Message string `json:"message"`
+}
+
+type billingController struct{}
+
+func (billingController) Load(router *requiem.Router) {
+	r := router.NewRestRouter("/items")
+	r.Get("/{id}", func(ctx requiem.HTTPContext) {}).
+		Returns(200, Item{}, "The item").
+		Returns(404, Problem{}, "Not found")
+}
+
+const inventoryDoc = `
+openapi: 3.0.3
+info: {title: Inventory, version: "2"}
+servers:
+  - url: https://inventory.internal/api
+security:
+  - apiKey: []
+tags:
+  - name: items
+    description: Stock
+paths:
+  /items/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string}}
+    get:
+      operationId: get_items_id
+      tags: [items]
+      responses:
+        200:
+          description: Stock of the item
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Item"}
+        404:
+          description: Not found
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Problem"}
+components:
+  schemas:
+    Item:
+      type: object
+      properties:
+        sku: {type: string}
+        stock: {type: integer}
+    Problem:
+      type: object
+      properties:
+        message: {type: string}
+  securitySchemes:
+    apiKey: {type: apiKey, in: header, name: X-API-Key}
+`
+
+func billingDoc() []byte {
+	s := requiem.NewServer(billingController{})
+	s.UseOpenAPI(requiem.OpenAPIConfig{Title: "Billing", Version: "1"})
+	return s.GetOpenAPISpec()
+}
+
+func merged(t *testing.T, services []Service, opts Options) map[string]interface{} {
+	t.Helper()
+	b, err := Merge(services, opts)
+	assert.NoError(t, err)
+	var doc map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &doc))
+	return doc
+}
+
+func ref(doc map[string]interface{}, path, status string) interface{} {
+	op := doc["paths"].(map[string]interface{})[path].(map[string]interface{})["get"].(map[string]interface{})
+	res := op["responses"].(map[string]interface{})[status].(map[string]interface{})
+	return res["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"].(map[string]interface{})["$ref"]
+}
+
+func TestMerge_PrefixesAndRenamesClashes(t *testing.T) {
+	doc := merged(t, []Service{
+		{Name: "billing", Spec: billingDoc(), PathPrefix: "/billing"},
+		{Name: "inventory", Spec: []byte(inventoryDoc), PathPrefix: "/inventory/"},
+	}, Options{Title: "Portal", Version: "1", Servers: []string{"https://gateway.example.com"}})
+
+	assert.Equal(t, "Portal", doc["info"].(map[string]interface{})["title"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "https://gateway.example.com"}}, doc["servers"])
+
+	paths := doc["paths"].(map[string]interface{})
+	assert.Len(t, paths, 2)
+	assert.Equal(t, "#/components/schemas/Item", ref(doc, "/billing/items/{id}", "200"))
+	assert.Equal(t, "#/components/schemas/InventoryItem", ref(doc, "/inventory/items/{id}", "200"))
+	// Identical definitions are shared.
+	assert.Equal(t, "#/components/schemas/Problem", ref(doc, "/inventory/items/{id}", "404"))
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	assert.Len(t, schemas, 3)
+	assert.Contains(t, schemas["InventoryItem"].(map[string]interface{})["properties"], "sku")
+
+	op := paths["/inventory/items/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, "inventory_get_items_id", op["operationId"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"apiKey": []interface{}{}}}, op["security"])
+	assert.Len(t, op["parameters"], 1)
+	assert.NotContains(t, op, "servers")
+	assert.Len(t, doc["tags"], 1)
+}
+
+func TestMerge_Namespace(t *testing.T) {
+	doc := merged(t, []Service{
+		{Name: "billing", Spec: billingDoc(), PathPrefix: "/billing"},
+		{Name: "inventory", Spec: []byte(inventoryDoc)},
+	}, Options{Namespace: true})
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, name := range []string{"BillingItem", "BillingProblem", "InventoryItem", "InventoryProblem"} {
+		assert.Contains(t, schemas, name)
+	}
+	schemes := doc["components"].(map[string]interface{})["securitySchemes"].(map[string]interface{})
+	assert.Contains(t, schemes, "InventoryapiKey")
+
+	op := doc["paths"].(map[string]interface{})["/items/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, []interface{}{map[string]interface{}{"InventoryapiKey": []interface{}{}}}, op["security"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "https://inventory.internal/api"}}, op["servers"])
+}
+
+func TestMerge_Conflicts(t *testing.T) {
+	_, err := Merge([]Service{
+		{Name: "a", Spec: []byte(inventoryDoc)},
+		{Name: "b", Spec: []byte(inventoryDoc)},
+	}, Options{})
+	assert.EqualError(t, err, "b: GET /items/{id} is also defined by a")
+
+	_, err = Merge([]Service{
+		{Name: "a", Spec: []byte(inventoryDoc)},
+		{Name: "b", Spec: []byte(`{"openapi": "3.1.0", "paths": {}}`)},
+	}, Options{})
+	assert.EqualError(t, err, "b: OpenAPI 3.1.0 cannot be merged with 3.0.3")
+
+	_, err = Merge([]Service{{Name: "c", Spec: []byte(`{"swagger": "2.0"}`)}}, Options{})
+	assert.EqualError(t, err, "c: not an OpenAPI document")
+}
+
+func TestMerge_OperationIDsAndTags(t *testing.T) {
+	reports := strings.NewReplacer(
+		"operationId: get_items_id", "operationId: inventory_get_items_id",
+		"description: Stock\n", "description: Stock reports\n    externalDocs: {url: https://docs.example.com/items}\n",
+	).Replace(inventoryDoc)
+	doc := merged(t, []Service{
+		{Name: "warehouse", Spec: []byte(inventoryDoc), PathPrefix: "/warehouse"},
+		{Name: "reports", Spec: []byte(reports), PathPrefix: "/reports"},
+		{Name: "inventory", Spec: []byte(inventoryDoc), PathPrefix: "/inventory"},
+	}, Options{})
+
+	paths := doc["paths"].(map[string]interface{})
+	id := func(path string) interface{} {
+		return paths[path].(map[string]interface{})["get"].(map[string]interface{})["operationId"]
+	}
+	assert.Equal(t, "get_items_id", id("/warehouse/items/{id}"))
+	assert.Equal(t, "inventory_get_items_id", id("/reports/items/{id}"))
+	assert.Equal(t, "inventory_get_items_id2", id("/inventory/items/{id}"))
+
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"name":         "items",
+		"description":  "Stock\n\nStock reports",
+		"externalDocs": map[string]interface{}{"url": "https://docs.example.com/items"},
+	}}, doc["tags"])
+}
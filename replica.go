@@ -0,0 +1,212 @@
+package requiem
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	replicaPluginName = "requiem:replicas"
+
+	// dbRoutingKey is the gorm.DB setting carrying a request's dbRouting. Only
+	// handles obtained through HTTPContext.DB carry one, so Router.DB always
+	// talks to the primary.
+	dbRoutingKey = "requiem:db_routing"
+
+	replicaPingTimeout = 2 * time.Second
+)
+
+// dbRouting is the per-request routing state shared by every statement issued
+// through HTTPContext.DB during one request.
+type dbRouting struct {
+	// readPreferred is set for GET/HEAD requests and routes marked ReadOnly.
+	readPreferred bool
+	// wrote flips on the first write, pinning the rest of the request to the
+	// primary so it reads its own writes despite replication lag.
+	wrote atomic.Bool
+}
+
+func newDBRouting(r *http.Request, rt *Route) *dbRouting {
+	ro := false
+	if rt != nil {
+		ro = rt.isReadOnly()
+	} else if r != nil {
+		ro = r.Method == http.MethodGet || r.Method == http.MethodHead
+	}
+	return &dbRouting{readPreferred: ro}
+}
+
+type replica struct {
+	name    string
+	db      *gorm.DB
+	healthy atomic.Bool
+}
+
+// replicaRouter is a GORM plugin that sends reads issued on a read-preferred
+// request to a healthy replica, round-robin. Writes, transactions, locking
+// reads and any read after a write in the same request go to the primary.
+type replicaRouter struct {
+	replicas []*replica
+	interval time.Duration
+	next     atomic.Uint32
+}
+
+func newReplicaRouter(replicas map[string]*gorm.DB, interval time.Duration) *replicaRouter {
+	names := make([]string, 0, len(replicas))
+	for name := range replicas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rr := &replicaRouter{interval: interval}
+	for _, name := range names {
+		r := &replica{name: name, db: replicas[name]}
+		r.healthy.Store(true)
+		rr.replicas = append(rr.replicas, r)
+	}
+	return rr
+}
+
+// replicaRouterOf returns the replica router attached to db, if any.
+func replicaRouterOf(db *gorm.DB) *replicaRouter {
+	if db == nil {
+		return nil
+	}
+	rr, _ := db.Config.Plugins[replicaPluginName].(*replicaRouter)
+	return rr
+}
+
+func (rr *replicaRouter) Name() string {
+	return replicaPluginName
+}
+
+func (rr *replicaRouter) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("requiem:replica_query", rr.routeRead); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("requiem:replica_row", rr.routeRead); err != nil {
+		return err
+	}
+	if err := cb.Create().Before("gorm:create").Register("requiem:replica_create", markWrote); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("requiem:replica_update", markWrote); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("requiem:replica_delete", markWrote); err != nil {
+		return err
+	}
+	return cb.Raw().Before("gorm:raw").Register("requiem:replica_raw", markWrote)
+}
+
+func routingOf(db *gorm.DB) *dbRouting {
+	v, ok := db.Get(dbRoutingKey)
+	if !ok {
+		return nil
+	}
+	routing, _ := v.(*dbRouting)
+	return routing
+}
+
+func markWrote(db *gorm.DB) {
+	if routing := routingOf(db); routing != nil {
+		routing.wrote.Store(true)
+	}
+}
+
+func (rr *replicaRouter) routeRead(db *gorm.DB) {
+	routing := routingOf(db)
+	if routing == nil || !routing.readPreferred || routing.wrote.Load() {
+		return
+	}
+	// Statements inside a transaction must stay on the transaction's connection.
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	// SELECT ... FOR UPDATE/SHARE needs the primary's locks.
+	if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return
+	}
+	if r := rr.pick(); r != nil {
+		db.Statement.ConnPool = r.db.ConnPool
+	}
+}
+
+// pick returns the next healthy replica, or nil to fall back to the primary.
+func (rr *replicaRouter) pick() *replica {
+	n := uint32(len(rr.replicas))
+	if n == 0 {
+		return nil
+	}
+	start := rr.next.Add(1)
+	for i := uint32(0); i < n; i++ {
+		r := rr.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// checkHealth pings every replica and records the result. It returns the
+// health of each replica keyed by name.
+func (rr *replicaRouter) checkHealth() map[string]bool {
+	out := make(map[string]bool, len(rr.replicas))
+	for _, r := range rr.replicas {
+		ok := pingDB(r.db) == nil
+		r.healthy.Store(ok)
+		out[r.name] = ok
+	}
+	return out
+}
+
+// monitor re-checks replica health every interval until the returned stop
+// function is called.
+func (rr *replicaRouter) monitor() func() {
+	interval := rr.interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				rr.checkHealth()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func pingDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), replicaPingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+// ReadOnly marks a non-GET route (e.g. a POST search endpoint) as read-only,
+// so HTTPContext.DB routes its reads to replicas and its MCP tool is annotated
+// as read-only. GET routes are read-only implicitly.
+func (rt *Route) ReadOnly() *Route {
+	rt.readOnly = true
+	return rt
+}
+
+func (rt *Route) isReadOnly() bool {
+	return rt.readOnly || rt.method == http.MethodGet || rt.method == http.MethodHead
+}
@@ -0,0 +1,105 @@
+package requiem
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type replicaItem struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+}
+
+type replicaController struct{}
+
+func (c replicaController) Load(router *Router) {
+	r := router.NewRestRouter("/items")
+	list := func(ctx HTTPContext) {
+		var items []replicaItem
+		ctx.DB().Order("id").Find(&items)
+		ctx.SendJSON(items)
+	}
+	r.Get("/", list)
+	r.Get("/write-then-read", func(ctx HTTPContext) {
+		ctx.DB().Create(&replicaItem{Name: "fresh"})
+		list(ctx)
+	})
+	r.Post("/search", list, nil).ReadOnly()
+	r.Post("/", list, nil)
+}
+
+func openReplicaTestDB(t *testing.T, name string, rows ...string) *gorm.DB {
+	t.Helper()
+	db := testDB(t, name, &replicaItem{})
+	for _, n := range rows {
+		db.Create(&replicaItem{Name: n})
+	}
+	return db
+}
+
+func replicaTestRouter(t *testing.T) (*Router, *gorm.DB) {
+	primary := openReplicaTestDB(t, "primary", "primary-row")
+	replicaDB := openReplicaTestDB(t, "replica", "replica-row")
+	assert.NoError(t, primary.Use(newReplicaRouter(map[string]*gorm.DB{"replica-1": replicaDB}, 0)))
+	return newRouter(defaultBasePath, primary, []IHttpController{replicaController{}, HealthcheckController{}}), replicaDB
+}
+
+func replicaNames(t *testing.T, r *Router, method, target string) []string {
+	t.Helper()
+	rec := serve(r, method, target, "", nil)
+	var items []replicaItem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &items))
+	names := []string{}
+	for _, it := range items {
+		names = append(names, it.Name)
+	}
+	return names
+}
+
+func TestReplicas_RoutesReadsByMethod(t *testing.T) {
+	r, _ := replicaTestRouter(t)
+
+	assert.Equal(t, []string{"replica-row"}, replicaNames(t, r, http.MethodGet, "/api/items/"))
+	assert.Equal(t, []string{"replica-row"}, replicaNames(t, r, http.MethodPost, "/api/items/search"))
+	assert.Equal(t, []string{"primary-row"}, replicaNames(t, r, http.MethodPost, "/api/items/"))
+}
+
+func TestReplicas_StickyPrimaryAfterWrite(t *testing.T) {
+	r, _ := replicaTestRouter(t)
+
+	assert.Equal(t, []string{"primary-row", "fresh"}, replicaNames(t, r, http.MethodGet, "/api/items/write-then-read"))
+}
+
+func TestReplicas_RouterDBAlwaysUsesPrimary(t *testing.T) {
+	r, _ := replicaTestRouter(t)
+
+	var items []replicaItem
+	r.DB.Find(&items)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "primary-row", items[0].Name)
+}
+
+func TestReplicas_UnhealthyReplicaDegradesReadiness(t *testing.T) {
+	r, replicaDB := replicaTestRouter(t)
+
+	rec := serve(r, http.MethodGet, "/api/healthcheck/ready", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	sqlDB, _ := replicaDB.DB()
+	sqlDB.Close()
+
+	rec = serve(r, http.MethodGet, "/api/healthcheck/ready", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var status map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, "degraded", status["status"])
+	assert.Equal(t, "unavailable", status["replicas"].(map[string]interface{})["replica-1"])
+
+	// Reads fall back to the primary while no replica is healthy.
+	assert.Equal(t, []string{"primary-row"}, replicaNames(t, r, http.MethodGet, "/api/items/"))
+}
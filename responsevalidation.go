@@ -0,0 +1,419 @@
+package requiem
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ResponseValidationMode selects what OpenAPIConfig.ValidateResponses does
+// when a response doesn't match its route's declared responses.
+type ResponseValidationMode int
+
+const (
+	// ResponseValidationOff skips response validation (default).
+	ResponseValidationOff ResponseValidationMode = iota
+	// ResponseValidationLog logs each violation and sends the response as is.
+	ResponseValidationLog
+	// ResponseValidationFail replaces a violating response with a 500
+	// describing the violations.
+	ResponseValidationFail
+	// ResponseValidationRecord keeps violations for ResponseViolations and
+	// AssertNoResponseViolations, and sends the response as is.
+	ResponseValidationRecord
+)
+
+// ResponseViolation is a response that didn't match its route's Returns.
+type ResponseViolation struct {
+	Method string
+	// Route is the route's path template.
+	Route  string
+	Status int
+	Reason string
+}
+
+func (v ResponseViolation) String() string {
+	return fmt.Sprintf("%s %s => %d: %s", v.Method, v.Route, v.Status, v.Reason)
+}
+
+// responseViolations collects the violations a router records in
+// ResponseValidationRecord mode.
+type responseViolations struct {
+	mu   sync.Mutex
+	list []ResponseViolation
+}
+
+func (v *responseViolations) add(found []ResponseViolation) {
+	v.mu.Lock()
+	v.list = append(v.list, found...)
+	v.mu.Unlock()
+}
+
+func (v *responseViolations) all() []ResponseViolation {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]ResponseViolation(nil), v.list...)
+}
+
+func (v *responseViolations) reset() {
+	v.mu.Lock()
+	v.list = nil
+	v.mu.Unlock()
+}
+
+// assertNone fails t for each recorded violation, then clears them.
+func (v *responseViolations) assertNone(t testingT) {
+	t.Helper()
+	for _, found := range v.all() {
+		t.Errorf("response violates the API contract: %s", found)
+	}
+	v.reset()
+}
+
+// testingT is the part of *testing.T AssertNoResponseViolations uses.
+type testingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// ResponseViolations returns the violations the router recorded in
+// ResponseValidationRecord mode since the last ResetResponseViolations.
+func (r *Router) ResponseViolations() []ResponseViolation {
+	return r.violations.all()
+}
+
+// ResetResponseViolations clears the router's recorded violations.
+func (r *Router) ResetResponseViolations() {
+	r.violations.reset()
+}
+
+// AssertNoResponseViolations fails t for each violation the router recorded,
+// then clears them. Pass a *testing.T.
+func (r *Router) AssertNoResponseViolations(t testingT) {
+	t.Helper()
+	r.violations.assertNone(t)
+}
+
+// bufferedResponse holds a handler's response until it has been validated.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+	wrote  bool
+}
+
+func (w *bufferedResponse) Header() http.Header { return w.header }
+
+func (w *bufferedResponse) WriteHeader(status int) {
+	if !w.wrote {
+		w.status = status
+		w.wrote = true
+	}
+}
+
+func (w *bufferedResponse) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.body.Write(b)
+}
+
+// validateResponse buffers the response written through ctx and, once the
+// handler returns, checks it against the route's declared responses before
+// passing it on according to the router's ResponseValidationMode.
+func (r *Router) validateResponse(ctx *HTTPContext) func() {
+	mode := r.validateResponses
+	rt := ctx.route
+	if mode == ResponseValidationOff || rt == nil {
+		return func() {}
+	}
+	out := ctx.Response
+	buf := &bufferedResponse{header: out.Header(), status: http.StatusOK}
+	ctx.Response = buf
+
+	return func() {
+		problems := checkResponse(rt, buf)
+		if len(problems) > 0 {
+			found := make([]ResponseViolation, 0, len(problems))
+			for _, p := range problems {
+				found = append(found, ResponseViolation{
+					Method: rt.method,
+					Route:  stripPathRegex(rt.path),
+					Status: buf.status,
+					Reason: p,
+				})
+			}
+			switch mode {
+			case ResponseValidationLog:
+				for _, v := range found {
+					Logger.Warn("Response violates the API contract: %s", v)
+				}
+			case ResponseValidationRecord:
+				r.violations.add(found)
+			case ResponseValidationFail:
+				// The handler's headers describe the response being replaced.
+				for k := range buf.header {
+					delete(buf.header, k)
+				}
+				body, _ := json.Marshal(map[string]interface{}{
+					"message":    "Response does not match the API contract",
+					"violations": problems,
+				})
+				out.Header().Set("Content-Type", "application/json")
+				out.WriteHeader(http.StatusInternalServerError)
+				out.Write(body)
+				return
+			}
+		}
+		out.WriteHeader(buf.status)
+		out.Write(buf.body.Bytes())
+	}
+}
+
+// checkResponse lists the ways a buffered response deviates from the route's
+// Returns declarations. Routes that declare no responses aren't checked.
+func checkResponse(rt *Route, res *bufferedResponse) []string {
+	if len(rt.responses) == 0 {
+		return nil
+	}
+	spec, ok := rt.responses[res.status]
+	if !ok {
+		declared := make([]string, 0, len(rt.responses))
+		for code := range rt.responses {
+			declared = append(declared, strconv.Itoa(code))
+		}
+		sort.Strings(declared)
+		return []string{fmt.Sprintf("status %d is not declared (declared: %s)", res.status, strings.Join(declared, ", "))}
+	}
+	if spec.typ == nil {
+		return nil
+	}
+
+	mt, _, _ := mime.ParseMediaType(res.header.Get("Content-Type"))
+	if mt != "" && !isJSONMediaType(mt) {
+		return nil
+	}
+	if res.body.Len() == 0 {
+		return []string{"body is empty but a schema is declared"}
+	}
+	var body interface{}
+	if err := json.Unmarshal(res.body.Bytes(), &body); err != nil {
+		return []string{"body is not valid JSON: " + err.Error()}
+	}
+
+	db := newDocBuilder()
+	schema := db.schemaFor(spec.typ)
+	v := &schemaValidator{schemas: db.schemas}
+	v.validate(schema, body, "$")
+	return v.problems
+}
+
+// schemaValidator checks decoded JSON against the subset of OpenAPI schema
+// keywords that schemaFor and applyValidateTag emit.
+type schemaValidator struct {
+	schemas  map[string]map[string]interface{}
+	problems []string
+}
+
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if target, ok := v.schemas[strings.TrimPrefix(ref, openapiRefPrefix)]; ok {
+			v.validate(target, value, path)
+		}
+		return
+	}
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); !nullable && len(schema) > 0 && !goNilEncodable(schema) {
+			v.fail(path, "is null")
+		}
+		return
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, s := range all {
+			v.validate(s.(map[string]interface{}), value, path)
+		}
+	}
+	if one, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, s := range one {
+			sub := &schemaValidator{schemas: v.schemas}
+			sub.validate(s.(map[string]interface{}), value, path)
+			if len(sub.problems) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "matches %d of the oneOf schemas, want 1", matches)
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !inEnum(enum, value) {
+		v.fail(path, "%v is not one of %v", value, enum)
+	}
+
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			v.fail(path, "is %s, want string", jsonKind(value))
+			return
+		}
+		n := len([]rune(s))
+		if min, ok := schemaNumber(schema["minLength"]); ok && float64(n) < min {
+			v.fail(path, "is shorter than %v", min)
+		}
+		if max, ok := schemaNumber(schema["maxLength"]); ok && float64(n) > max {
+			v.fail(path, "is longer than %v", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+				v.fail(path, "does not match %s", pattern)
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			v.fail(path, "is %s, want %s", jsonKind(value), typ)
+			return
+		}
+		if typ == "integer" && n != float64(int64(n)) {
+			v.fail(path, "is %v, want integer", n)
+		}
+		exclusiveMin, _ := schema["exclusiveMinimum"].(bool)
+		if min, ok := schemaNumber(schema["minimum"]); ok && (n < min || exclusiveMin && n == min) {
+			v.fail(path, "is %v, below the minimum %v", n, min)
+		}
+		exclusiveMax, _ := schema["exclusiveMaximum"].(bool)
+		if max, ok := schemaNumber(schema["maximum"]); ok && (n > max || exclusiveMax && n == max) {
+			v.fail(path, "is %v, above the maximum %v", n, max)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.fail(path, "is %s, want boolean", jsonKind(value))
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			v.fail(path, "is %s, want array", jsonKind(value))
+			return
+		}
+		if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(items)) < min {
+			v.fail(path, "has fewer than %v items", min)
+		}
+		if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(items)) > max {
+			v.fail(path, "has more than %v items", max)
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				v.validate(itemSchema, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.fail(path, "is %s, want object", jsonKind(value))
+			return
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range requiredNames(schema["required"]) {
+			if _, ok := obj[name]; !ok {
+				v.fail(path, "is missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := props[k].(map[string]interface{}); ok {
+				v.validate(ps, obj[k], path+"."+k)
+			} else if ap, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				v.validate(ap, obj[k], path+"."+k)
+			}
+		}
+	}
+}
+
+// goNilEncodable reports whether schema comes from a Go slice or map, whose
+// nil value encoding/json writes as null.
+func goNilEncodable(schema map[string]interface{}) bool {
+	switch schema["type"] {
+	case "array":
+		return true
+	case "object":
+		_, isMap := schema["additionalProperties"]
+		return isMap && schema["properties"] == nil
+	case "string":
+		return schema["format"] == "byte"
+	}
+	return false
+}
+
+func requiredNames(v interface{}) []string {
+	switch r := v.(type) {
+	case []string:
+		return r
+	case []interface{}:
+		out := make([]string, 0, len(r))
+		for _, s := range r {
+			if name, ok := s.(string); ok {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if n, ok := schemaNumber(e); ok {
+			if f, ok := value.(float64); ok && f == n {
+				return true
+			}
+			continue
+		}
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
@@ -0,0 +1,137 @@
+package requiem
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type driftController struct{}
+
+type taggedWidget struct {
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags"`
+	Attrs map[string]string `json:"attrs"`
+}
+
+func (driftController) Load(router *Router) {
+	r := router.NewRestRouter("/drift")
+	r.Get("/ok", func(ctx HTTPContext) {
+		ctx.SendJSON(Widget{ID: "1", Name: "Gear", Quantity: 2})
+	}).Returns(200, Widget{}, "OK")
+
+	r.Get("/bad-body", func(ctx HTTPContext) {
+		ctx.SendJSON(map[string]interface{}{"id": 1, "quantity": 5000})
+	}).Returns(200, Widget{}, "OK")
+
+	r.Get("/bad-status", func(ctx HTTPContext) {
+		ctx.SendStatus(http.StatusTeapot)
+	}).Returns(200, Widget{}, "OK").Returns(404, nil, "Not found")
+
+	r.Get("/nil-fields", func(ctx HTTPContext) {
+		ctx.SendJSON(taggedWidget{Name: "x"})
+	}).Returns(200, taggedWidget{}, "OK")
+
+	r.Get("/bad-status-headers", func(ctx HTTPContext) {
+		ctx.Response.Header().Set("X-Widget", "1")
+		ctx.Response.Header().Set("Content-Type", "text/plain")
+		ctx.Response.WriteHeader(http.StatusTeapot)
+	}).Returns(200, Widget{}, "OK")
+
+	r.Get("/undocumented", func(ctx HTTPContext) {
+		ctx.SendStatus(http.StatusTeapot)
+	})
+}
+
+func driftRouter(mode ResponseValidationMode) *Router {
+	return newRouter("/api", nil, []IHttpController{
+		driftController{},
+		&openapiController{cfg: OpenAPIConfig{Title: "T", Version: "1", ValidateResponses: mode}},
+	})
+}
+
+func TestValidateResponses_Record(t *testing.T) {
+	r := driftRouter(ResponseValidationRecord)
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/api/drift/ok", "", nil).Code)
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/api/drift/bad-body", "", nil).Code)
+	assert.Equal(t, http.StatusTeapot, serve(r, http.MethodGet, "/api/drift/bad-status", "", nil).Code)
+	assert.Equal(t, http.StatusTeapot, serve(r, http.MethodGet, "/api/drift/undocumented", "", nil).Code)
+
+	var reasons []string
+	for _, v := range r.ResponseViolations() {
+		reasons = append(reasons, fmt.Sprintf("%s %d %s", v.Route, v.Status, v.Reason))
+	}
+	assert.Equal(t, []string{
+		`/drift/bad-body 200 $: is missing required property "name"`,
+		`/drift/bad-body 200 $.id: is number, want string`,
+		`/drift/bad-body 200 $.quantity: is 5000, above the maximum 999`,
+		`/drift/bad-status 418 status 418 is not declared (declared: 200, 404)`,
+	}, reasons)
+
+	rec := &recordingT{}
+	r.AssertNoResponseViolations(rec)
+	assert.Len(t, rec.errors, 4)
+	assert.Empty(t, r.ResponseViolations())
+}
+
+func TestValidateResponses_Fail(t *testing.T) {
+	r := driftRouter(ResponseValidationFail)
+
+	rec := serve(r, http.MethodGet, "/api/drift/ok", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var w Widget
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &w))
+	assert.Equal(t, "Gear", w.Name)
+
+	// Nil slices and maps encode as null, which is valid handler output.
+	rec = serve(r, http.MethodGet, "/api/drift/nil-fields", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"name":"x","tags":null,"attrs":null}`, rec.Body.String())
+
+	rec = serve(r, http.MethodGet, "/api/drift/bad-status", "", nil)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "status 418 is not declared")
+
+	// The 500 doesn't keep the replaced response's headers.
+	rec = serve(r, http.MethodGet, "/api/drift/bad-status-headers", "", nil)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, []string{"application/json"}, rec.Header().Values("Content-Type"))
+	assert.Empty(t, rec.Header().Get("X-Widget"))
+}
+
+func TestValidateResponses_RecordedPerServer(t *testing.T) {
+	record := OpenAPIConfig{Title: "T", Version: "1", ValidateResponses: ResponseValidationRecord}
+	a, b := NewServer(driftController{}), NewServer(driftController{})
+	a.UseOpenAPI(record)
+	b.UseOpenAPI(record)
+
+	r := newRouter(a.BasePath, nil, a.controllers)
+	serve(r, http.MethodGet, "/api/drift/bad-status", "", nil)
+	assert.Len(t, a.ResponseViolations(), 1)
+	assert.Empty(t, b.ResponseViolations())
+
+	rec := &recordingT{}
+	a.AssertNoResponseViolations(rec)
+	assert.Len(t, rec.errors, 1)
+	assert.Empty(t, a.ResponseViolations())
+}
+
+func TestValidateResponses_OffPassesThrough(t *testing.T) {
+	r := driftRouter(ResponseValidationOff)
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/api/drift/bad-body", "", nil).Code)
+	assert.Empty(t, r.ResponseViolations())
+}
+
+type recordingT struct {
+	errors []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
@@ -1,8 +1,11 @@
 package requiem
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"reflect"
+	"sync"
 
 	"github.com/gorilla/mux"
 	validator "gopkg.in/go-playground/validator.v9"
@@ -15,6 +18,27 @@ type Router struct {
 	DB        *gorm.DB
 	routes    []*Route
 	basePath  string
+	tenancy   *TenantConfig
+	audit     *auditor
+	trash     []trashTarget
+	webhooks  []*Webhook
+
+	webhookDispatcher *webhookDispatcher
+	// loaded are run once every controller has loaded, for addons whose
+	// endpoints are placed relative to the routes the others registered.
+	loaded []func()
+
+	validateRequests  bool
+	validateResponses ResponseValidationMode
+	violations        *responseViolations
+	mock              *MockConfig
+
+	// mu guards routes, generation and MuxRouter's route table, so routes
+	// can be registered while the router is serving.
+	mu sync.RWMutex
+	// generation counts changes to the routes, telling the OpenAPI addon when
+	// its cached spec is stale.
+	generation uint64
 }
 
 // IHttpController represents a REST API that can be loaded into a router
@@ -28,6 +52,9 @@ type RestRouter struct {
 	parent *Router
 	prefix string
 	DB     *gorm.DB
+
+	security []SecurityRequirement
+	trash    *TrashRoutes
 }
 
 // Load adds all of the given REST controller routes into the router
@@ -35,6 +62,18 @@ func (r *Router) load(controllers []IHttpController) {
 	for _, c := range controllers {
 		c.Load(r)
 	}
+	for _, f := range r.loaded {
+		f()
+	}
+	r.loaded = nil
+}
+
+// mount registers an endpoint of an addon, one not described by the spec,
+// under the router lock.
+func (r *Router) mount(method, path string, handler http.HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.MuxRouter.HandleFunc(path, handler).Methods(method)
 }
 
 // PrintRoutes logs all of the router's registered paths
@@ -56,49 +95,136 @@ func (r *Router) printRoutes() {
 	Logger.Info("===============================================================")
 }
 
+// InvalidateSpec marks the OpenAPI document and the MCP tool list stale, so
+// the next request for them rebuilds them. Registering a route does this
+// itself; call it after other changes to what the spec describes, such as
+// metadata chained onto a route registered at runtime or routes excluded by
+// a feature flag.
+func (r *Router) InvalidateSpec() {
+	r.mu.Lock()
+	r.generation++
+	r.mu.Unlock()
+}
+
+// ServeHTTP dispatches requests through MuxRouter, holding the router's read
+// lock while mux matches the route so registrations can't race with it. The
+// lock is released by releaseRouterLock before any matched handler runs, so
+// routes can be registered while serving, including from a handler.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var once sync.Once
+	r.mu.RLock()
+	unlock := func() { once.Do(r.mu.RUnlock) }
+	defer unlock()
+	r.MuxRouter.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), routerUnlockKey{}, unlock)))
+}
+
+type routerUnlockKey struct{}
+
+// releaseRouterLock is MuxRouter's outermost middleware. Mux applies it once
+// a route has matched, so it releases the lock taken by Router.ServeHTTP
+// before the route's middlewares and handler run.
+func releaseRouterLock(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if unlock, ok := req.Context().Value(routerUnlockKey{}).(func()); ok {
+			unlock()
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
 // newRouter initializes a new router starting at the given path
 func newRouter(path string, db *gorm.DB, controllers []IHttpController) *Router {
 	mr := mux.NewRouter().PathPrefix(path).Subrouter()
-	r := &Router{MuxRouter: mr, DB: db, routes: []*Route{}, basePath: path}
+	mr.Use(releaseRouterLock)
+	r := &Router{MuxRouter: mr, DB: db, routes: []*Route{}, basePath: path, violations: &responseViolations{}}
 	r.load(controllers)
 	return r
 }
 
 // HandleFunc wraps the router HandleFunc to inject an HTTPContext for use
 // by subsequent handlers.
-func (r *RestRouter) handleFunc(method string, path string, handle func(HTTPContext), interceptors ...HTTPInterceptor) {
+func (r *RestRouter) handleFunc(rt *Route, path string, handle func(HTTPContext), interceptors ...HTTPInterceptor) {
+	parent := r.parent
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
 	r.router.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		ctx := HTTPContext{Request: r, Response: w, attributes: make(map[string]interface{})}
+		ctx := newHTTPContext(w, r, nil, parent, rt)
+		defer parent.auditRequest(&ctx)()
+		defer parent.validateResponse(&ctx)()
 
-		if processInterceptors(interceptors, ctx) {
-			handle(ctx)
+		// Interceptors (auth) run first, so callers they reject learn nothing
+		// about the route's contract from validation errors.
+		if !processInterceptors(interceptors, ctx) {
+			return
+		}
+		if parent.validateRequests {
+			if issues := validateParams(rt, r); len(issues) > 0 {
+				sendValidationError(ctx, issues)
+				return
+			}
 		}
-	}).Methods(method)
+		if parent.mock != nil {
+			parent.serveMock(ctx, rt)
+			return
+		}
+
+		handle(ctx)
+	}).Methods(rt.method)
 }
 
-func (r *RestRouter) handleFuncBody(method string, path string, handle func(HTTPContext), v interface{}, interceptors ...HTTPInterceptor) {
+func (r *RestRouter) handleFuncBody(rt *Route, path string, handle func(HTTPContext), v interface{}, interceptors ...HTTPInterceptor) {
 	if v == nil {
-		Logger.Fatal("[%s] %s => Body interface cannot be nil", method, path)
+		Logger.Fatal("[%s] %s => Body interface cannot be nil", rt.method, path)
 	}
 
+	parent := r.parent
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
 	r.router.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		b := ReadJSON(r.Body, v)
+		b, err := readBody(r, rt, v)
+		ctx := newHTTPContext(w, r, b, parent, rt)
+		defer parent.auditRequest(&ctx)()
+		defer parent.validateResponse(&ctx)()
 
-		if validator.New().Struct(b) != nil {
-			SendStatus(w, http.StatusBadRequest)
+		if !processInterceptors(interceptors, ctx) {
 			return
 		}
-
-		ctx := HTTPContext{Request: r, Response: w, Body: b, attributes: make(map[string]interface{})}
-
-		if processInterceptors(interceptors, ctx) {
-			handle(ctx)
+		if errors.Is(err, errUnsupportedMediaType) {
+			ctx.SendStatus(http.StatusUnsupportedMediaType)
+			return
+		}
+		if errors.As(err, &jsonDecodeError{}) && !parent.validateRequests {
+			err = nil
+		}
+		if err == nil {
+			err = validator.New().Struct(b)
+		}
+		if parent.validateRequests {
+			issues := validateParams(rt, r)
+			if err != nil {
+				issues = append(issues, bodyIssues(err)...)
+			}
+			if len(issues) > 0 {
+				sendValidationError(ctx, issues)
+				return
+			}
+		} else if err != nil {
+			ctx.SendStatus(http.StatusBadRequest)
+			return
+		}
+		if parent.mock != nil {
+			parent.serveMock(ctx, rt)
+			return
 		}
-	}).Methods(method)
+
+		handle(ctx)
+	}).Methods(rt.method)
 }
 
 // NewRestRouter initializes a new REST router on at the given path
 func (r *Router) NewRestRouter(path string) *RestRouter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return &RestRouter{
 		router: r.MuxRouter.PathPrefix(path).Subrouter(),
 		parent: r,
@@ -109,34 +235,41 @@ func (r *Router) NewRestRouter(path string) *RestRouter {
 
 // Get handles GET HTTP requests for the given path
 func (r *RestRouter) Get(path string, handle func(HTTPContext), interceptors ...HTTPInterceptor) *Route {
-	r.handleFunc(http.MethodGet, path, handle, interceptors...)
-	return r.register(http.MethodGet, path, nil)
+	rt := r.register(http.MethodGet, path, nil)
+	r.handleFunc(rt, path, handle, interceptors...)
+	return rt
 }
 
 // Post handles POST HTTP requests for the given path
 func (r *RestRouter) Post(path string, handle func(HTTPContext), v interface{}, interceptors ...HTTPInterceptor) *Route {
+	rt := r.register(http.MethodPost, path, v)
 	if v == nil {
-		r.handleFunc(http.MethodPost, path, handle, interceptors...)
+		r.handleFunc(rt, path, handle, interceptors...)
 	} else {
-		r.handleFuncBody(http.MethodPost, path, handle, v, interceptors...)
+		r.handleFuncBody(rt, path, handle, v, interceptors...)
 	}
-	return r.register(http.MethodPost, path, v)
+	return rt
 }
 
 // Put handles PUT HTTP requests for the given path
 func (r *RestRouter) Put(path string, handle func(HTTPContext), v interface{}, interceptors ...HTTPInterceptor) *Route {
-	r.handleFuncBody(http.MethodPut, path, handle, v, interceptors...)
-	return r.register(http.MethodPut, path, v)
+	rt := r.register(http.MethodPut, path, v)
+	r.handleFuncBody(rt, path, handle, v, interceptors...)
+	return rt
 }
 
 // Delete handles DELETE HTTP requests for the given path
 func (r *RestRouter) Delete(path string, handle func(HTTPContext), v interface{}, interceptors ...HTTPInterceptor) *Route {
+	if r.trash != nil && stripPathRegex(path) == "/{id}" {
+		Logger.Fatal("[DELETE] %s => Already registered by Trash, which replaces Delete", r.prefix+path)
+	}
+	rt := r.register(http.MethodDelete, path, v)
 	if v == nil {
-		r.handleFunc(http.MethodDelete, path, handle, interceptors...)
+		r.handleFunc(rt, path, handle, interceptors...)
 	} else {
-		r.handleFuncBody(http.MethodDelete, path, handle, v, interceptors...)
+		r.handleFuncBody(rt, path, handle, v, interceptors...)
 	}
-	return r.register(http.MethodDelete, path, v)
+	return rt
 }
 
 func (r *RestRouter) register(method, path string, v interface{}) *Route {
@@ -145,12 +278,18 @@ func (r *RestRouter) register(method, path string, v interface{}) *Route {
 		t = reflect.TypeOf(v)
 	}
 	rt := &Route{
+		router:       r.parent,
 		method:       method,
 		path:         r.prefix + path,
 		routerPrefix: r.prefix,
 		bodyType:     t,
+		security:     append([]SecurityRequirement(nil), r.security...),
 	}
+	r.parent.mu.Lock()
 	r.parent.routes = append(r.parent.routes, rt)
+	r.parent.assignOperationIDs()
+	r.parent.generation++
+	r.parent.mu.Unlock()
 	return rt
 }
 
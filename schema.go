@@ -1,14 +1,22 @@
 package requiem
 
 import (
+	"encoding"
+	"encoding/json"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-var timeType = reflect.TypeOf(time.Time{})
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
 
 // OpenAPISchemaProvider lets a type declare its own OpenAPI schema fragment.
 // requiem honors it before falling back to reflection, so types whose JSON
@@ -32,17 +40,50 @@ func (db *docBuilder) schemaFor(t reflect.Type) map[string]interface{} {
 	if t == timeType {
 		return map[string]interface{}{"type": "string", "format": "date-time"}
 	}
+	// gorm.DeletedAt marshals as a timestamp, or null while the row is live.
+	if t == deletedAtType {
+		return map[string]interface{}{"type": "string", "format": "date-time", "nullable": true}
+	}
+	// Uploaded files are documented as binary strings.
+	if t == fileHeaderType {
+		return map[string]interface{}{"type": "string", "format": "binary"}
+	}
+	// time.Duration marshals as its int64 nanosecond count. The format
+	// registry's "duration" is an ISO 8601 string, so the unit is described instead.
+	if t == durationType {
+		return map[string]interface{}{"type": "integer", "format": "int64", "description": "Duration in nanoseconds."}
+	}
+	// UUID types of the common packages (google/uuid, gofrs/uuid) are
+	// [16]byte arrays that marshal as their canonical string form.
+	if t.Name() == "UUID" && t.Kind() == reflect.Array && t.Len() == 16 {
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	}
+	// encoding/json marshals TextMarshalers as strings, unless they also
+	// implement json.Marshaler, whose output shape is unknown.
+	if marshalsAsText(t) {
+		return map[string]interface{}{"type": "string"}
+	}
 
 	switch t.Kind() {
 	case reflect.String:
 		return map[string]interface{}{"type": "string"}
 	case reflect.Bool:
 		return map[string]interface{}{"type": "boolean"}
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return map[string]interface{}{"type": "integer"}
-	case reflect.Float32, reflect.Float64:
-		return map[string]interface{}{"type": "number"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Uint8, reflect.Uint16:
+		return map[string]interface{}{"type": "integer", "format": "int32", "minimum": 0}
+	case reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Uint, reflect.Uint64:
+		// Values above math.MaxInt64 don't fit int64, so these get their own format.
+		return map[string]interface{}{"type": "integer", "format": "uint64", "minimum": 0}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
 	case reflect.Slice, reflect.Array:
 		if t.Elem().Kind() == reflect.Uint8 {
 			return map[string]interface{}{"type": "string", "format": "byte"}
@@ -59,12 +100,25 @@ func (db *docBuilder) schemaFor(t reflect.Type) map[string]interface{} {
 	case reflect.Struct:
 		return db.structRef(t)
 	case reflect.Interface:
+		if u := unionFor(t); u != nil {
+			return db.unionSchema(t, u)
+		}
 		return map[string]interface{}{}
 	}
 
 	return map[string]interface{}{}
 }
 
+func marshalsAsText(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	implements := func(iface reflect.Type) bool {
+		return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+	}
+	return implements(textMarshalerType) && !implements(jsonMarshalerType)
+}
+
 // customSchemaFor returns a schema produced by OpenAPISchemaProvider when the
 // type (or its pointer) implements it. Skips reflect.Interface to avoid the
 // special case of zero-value interface instantiation.
@@ -96,6 +150,13 @@ func (db *docBuilder) structRef(t reflect.Type) map[string]interface{} {
 }
 
 func (db *docBuilder) buildStructSchema(t reflect.Type) map[string]interface{} {
+	return db.buildObjectSchema(t, "json")
+}
+
+// buildObjectSchema builds a struct's object schema with properties named by
+// nameTag ("json", or "form" for form-bound bodies, which falls back to the
+// json tag).
+func (db *docBuilder) buildObjectSchema(t reflect.Type, nameTag string) map[string]interface{} {
 	properties := map[string]interface{}{}
 	required := []string{}
 
@@ -108,14 +169,18 @@ func (db *docBuilder) buildStructSchema(t reflect.Type) map[string]interface{} {
 			}
 
 			jsonTag := f.Tag.Get("json")
-			if jsonTag == "-" {
+			nameSource := jsonTag
+			if tag := f.Tag.Get(nameTag); tag != "" {
+				nameSource = tag
+			}
+			if nameSource == "-" {
 				continue
 			}
 
 			// Anonymous embedded struct with no explicit json tag: Go's
 			// encoding/json promotes its exported fields to the parent. Mirror
 			// that in the schema so the generated client sees a flat object.
-			if f.Anonymous && jsonTag == "" {
+			if f.Anonymous && nameSource == "" {
 				ft := f.Type
 				for ft.Kind() == reflect.Ptr {
 					ft = ft.Elem()
@@ -127,15 +192,17 @@ func (db *docBuilder) buildStructSchema(t reflect.Type) map[string]interface{} {
 			}
 
 			name := f.Name
-			omitEmpty := false
+			if n := strings.Split(nameSource, ",")[0]; n != "" {
+				name = n
+			}
+			omitEmpty, asString := false, false
 			if jsonTag != "" {
-				parts := strings.Split(jsonTag, ",")
-				if parts[0] != "" {
-					name = parts[0]
-				}
-				for _, p := range parts[1:] {
-					if p == "omitempty" {
+				for _, p := range strings.Split(jsonTag, ",")[1:] {
+					switch p {
+					case "omitempty":
 						omitEmpty = true
+					case "string":
+						asString = nameTag == "json"
 					}
 				}
 			}
@@ -143,6 +210,20 @@ func (db *docBuilder) buildStructSchema(t reflect.Type) map[string]interface{} {
 			schema := db.schemaFor(f.Type)
 			if _, isRef := schema["$ref"]; !isRef {
 				applyValidateTag(schema, f.Tag.Get("validate"))
+				// The ",string" option quotes numbers and booleans; the format
+				// and bounds still describe the quoted value.
+				if typ, _ := schema["type"].(string); asString && (typ == "integer" || typ == "number" || typ == "boolean") {
+					schema["type"] = "string"
+				}
+				applyExampleTag(schema, f.Tag.Get("example"))
+			}
+			// Pointer fields marshal as null when nil. A $ref can't carry
+			// siblings in 3.0, so it's wrapped in allOf.
+			if f.Type.Kind() == reflect.Ptr {
+				if _, isRef := schema["$ref"]; isRef {
+					schema = map[string]interface{}{"allOf": []interface{}{schema}}
+				}
+				schema["nullable"] = true
 			}
 			properties[name] = schema
 
@@ -169,6 +250,10 @@ func hasValidateRule(tag, name string) bool {
 		return false
 	}
 	for _, p := range strings.Split(tag, ",") {
+		// Rules after dive apply to elements, not the field itself.
+		if p == "dive" {
+			return false
+		}
 		if p == name || strings.HasPrefix(p, name+"=") {
 			return true
 		}
@@ -176,46 +261,189 @@ func hasValidateRule(tag, name string) bool {
 	return false
 }
 
+// validateFormats maps validator rules to OpenAPI string formats.
+var validateFormats = map[string]string{
+	"email":    "email",
+	"url":      "uri",
+	"uri":      "uri",
+	"uuid":     "uuid",
+	"uuid3":    "uuid",
+	"uuid4":    "uuid",
+	"uuid5":    "uuid",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"ip":       "ip",
+	"hostname": "hostname",
+	"base64":   "byte",
+}
+
+// validatePatterns maps validator rules to the regular expressions the
+// validator itself checks them with.
+var validatePatterns = map[string]string{
+	"alpha":       `^[a-zA-Z]+$`,
+	"alphanum":    `^[a-zA-Z0-9]+$`,
+	"numeric":     `^[-+]?[0-9]+(?:\.[0-9]+)?$`,
+	"number":      `^[0-9]+$`,
+	"hexadecimal": `^[0-9a-fA-F]+$`,
+	"hexcolor":    `^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`,
+	"e164":        `^\+[1-9]?[0-9]{7,14}$`,
+	"ascii":       `^[\x00-\x7F]*$`,
+}
+
+// validateStructural are rules that shape validation itself and have no
+// schema counterpart.
+var validateStructural = map[string]bool{
+	"required": true, "omitempty": true, "dive": true, "keys": true,
+	"endkeys": true, "structonly": true, "nostructlevel": true, "-": true,
+}
+
+var (
+	warnedRulesMu sync.Mutex
+	warnedRules   = map[string]bool{}
+)
+
+// warnUnmappedRule logs, once per rule, a validate rule that the schema
+// doesn't reflect, so drift between spec and validation is visible.
+func warnUnmappedRule(rule string) {
+	warnedRulesMu.Lock()
+	defer warnedRulesMu.Unlock()
+	if warnedRules[rule] || Logger == nil {
+		return
+	}
+	warnedRules[rule] = true
+	Logger.Warn("OpenAPI: validate rule %q is not reflected in the generated schema", rule)
+}
+
+// parseBound parses a numeric rule parameter, keeping integers integral so
+// min=1 renders as 1 rather than 1.0.
+func parseBound(s string) (interface{}, bool) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
 func applyValidateTag(schema map[string]interface{}, tag string) {
 	if tag == "" {
 		return
 	}
+	parts := strings.Split(tag, ",")
+	for i, part := range parts {
+		if part == "dive" {
+			// Remaining rules apply to slice elements or map values.
+			elem, _ := schema["items"].(map[string]interface{})
+			if elem == nil {
+				elem, _ = schema["additionalProperties"].(map[string]interface{})
+			}
+			if _, isRef := elem["$ref"]; elem != nil && !isRef {
+				applyValidateTag(elem, strings.Join(parts[i+1:], ","))
+			}
+			return
+		}
+		applyValidateRule(schema, part)
+	}
+}
+
+func applyValidateRule(schema map[string]interface{}, rule string) {
 	typ, _ := schema["type"].(string)
-	for _, part := range strings.Split(tag, ",") {
-		kv := strings.SplitN(part, "=", 2)
-		if len(kv) != 2 {
-			continue
+	name, param := rule, ""
+	if kv := strings.SplitN(rule, "=", 2); len(kv) == 2 {
+		name, param = kv[0], kv[1]
+	}
+
+	if validateStructural[name] {
+		return
+	}
+	if format, ok := validateFormats[name]; ok && typ == "string" {
+		schema["format"] = format
+		return
+	}
+	if pattern, ok := validatePatterns[name]; ok && typ == "string" {
+		schema["pattern"] = pattern
+		return
+	}
+
+	// Length bounds on strings, arrays and maps, value bounds on numbers.
+	// gt/lt are exclusive: for lengths that's the next integer, for numbers
+	// the 3.0 exclusiveMinimum/exclusiveMaximum flags.
+	bound := func(minimum bool, exclusive bool) bool {
+		v, ok := parseBound(param)
+		if !ok {
+			return false
 		}
-		switch kv[0] {
-		case "min":
-			if n, err := strconv.Atoi(kv[1]); err == nil {
-				switch typ {
-				case "string":
-					schema["minLength"] = n
-				case "integer", "number":
-					schema["minimum"] = n
-				case "array":
-					schema["minItems"] = n
+		switch typ {
+		case "string", "array", "object":
+			n, isInt := v.(int64)
+			if !isInt {
+				return false
+			}
+			if exclusive {
+				if minimum {
+					n++
+				} else {
+					n--
 				}
 			}
-		case "max":
-			if n, err := strconv.Atoi(kv[1]); err == nil {
-				switch typ {
-				case "string":
-					schema["maxLength"] = n
-				case "integer", "number":
-					schema["maximum"] = n
-				case "array":
-					schema["maxItems"] = n
+			key := map[string]map[bool]string{
+				"string": {true: "minLength", false: "maxLength"},
+				"array":  {true: "minItems", false: "maxItems"},
+				"object": {true: "minProperties", false: "maxProperties"},
+			}[typ][minimum]
+			schema[key] = n
+		case "integer", "number":
+			if minimum {
+				schema["minimum"] = v
+				if exclusive {
+					schema["exclusiveMinimum"] = true
+				}
+			} else {
+				schema["maximum"] = v
+				if exclusive {
+					schema["exclusiveMaximum"] = true
 				}
 			}
-		case "oneof":
-			vals := strings.Fields(kv[1])
-			enum := make([]interface{}, 0, len(vals))
-			for _, v := range vals {
-				enum = append(enum, v)
+		default:
+			return false
+		}
+		return true
+	}
+
+	mapped := false
+	switch name {
+	case "min", "gte":
+		mapped = bound(true, false)
+	case "max", "lte":
+		mapped = bound(false, false)
+	case "gt":
+		mapped = bound(true, true)
+	case "lt":
+		mapped = bound(false, true)
+	case "len":
+		mapped = bound(true, false) && bound(false, false)
+	case "oneof":
+		vals := strings.Fields(param)
+		enum := make([]interface{}, 0, len(vals))
+		for _, v := range vals {
+			if typ == "integer" || typ == "number" {
+				if n, ok := parseBound(v); ok {
+					enum = append(enum, n)
+					continue
+				}
 			}
-			schema["enum"] = enum
+			enum = append(enum, v)
 		}
+		schema["enum"] = enum
+		mapped = true
+	case "unique":
+		if typ == "array" {
+			schema["uniqueItems"] = true
+			mapped = true
+		}
+	}
+	if !mapped {
+		warnUnmappedRule(name)
 	}
 }
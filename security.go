@@ -0,0 +1,206 @@
+package requiem
+
+import "sort"
+
+// Security scheme types, as used in SecurityScheme.Type.
+const (
+	SecurityTypeHTTP          = "http"
+	SecurityTypeAPIKey        = "apiKey"
+	SecurityTypeOAuth2        = "oauth2"
+	SecurityTypeOpenIDConnect = "openIdConnect"
+)
+
+// SecurityScheme describes one way clients authenticate, emitted under
+// components.securitySchemes. Use the BearerAuth, BasicAuth, APIKeyAuth and
+// OAuth2Auth helpers for the common cases.
+type SecurityScheme struct {
+	// Type is one of the SecurityType* constants.
+	Type        string
+	Description string
+	// Scheme is the HTTP auth scheme ("bearer", "basic") for SecurityTypeHTTP.
+	Scheme string
+	// BearerFormat hints at the bearer token format, e.g. "JWT".
+	BearerFormat string
+	// In ("header", "query" or "cookie") and Name locate the key for
+	// SecurityTypeAPIKey.
+	In   string
+	Name string
+	// Flows configures SecurityTypeOAuth2.
+	Flows OAuthFlows
+	// OpenIDConnectURL is the discovery URL for SecurityTypeOpenIDConnect.
+	OpenIDConnectURL string
+}
+
+// OAuthFlows lists the OAuth2 flows a SecurityTypeOAuth2 scheme supports.
+type OAuthFlows struct {
+	Implicit          *OAuthFlow
+	Password          *OAuthFlow
+	ClientCredentials *OAuthFlow
+	AuthorizationCode *OAuthFlow
+}
+
+// OAuthFlow configures a single OAuth2 flow. Scopes maps scope names to their
+// descriptions.
+type OAuthFlow struct {
+	AuthorizationURL string
+	TokenURL         string
+	RefreshURL       string
+	Scopes           map[string]string
+}
+
+// SecurityRequirement maps scheme names to the OAuth2 scopes they need (empty
+// for other scheme types). All schemes in one requirement apply together.
+type SecurityRequirement map[string][]string
+
+// BearerAuth returns an HTTP bearer scheme, e.g. BearerAuth("JWT").
+func BearerAuth(format string) SecurityScheme {
+	return SecurityScheme{Type: SecurityTypeHTTP, Scheme: "bearer", BearerFormat: format}
+}
+
+// BasicAuth returns an HTTP basic auth scheme.
+func BasicAuth() SecurityScheme {
+	return SecurityScheme{Type: SecurityTypeHTTP, Scheme: "basic"}
+}
+
+// APIKeyAuth returns an API key scheme read from the named header, query
+// param or cookie.
+func APIKeyAuth(in, name string) SecurityScheme {
+	return SecurityScheme{Type: SecurityTypeAPIKey, In: in, Name: name}
+}
+
+// OAuth2Auth returns an OAuth2 scheme with the given flows.
+func OAuth2Auth(flows OAuthFlows) SecurityScheme {
+	return SecurityScheme{Type: SecurityTypeOAuth2, Flows: flows}
+}
+
+// Security adds a security requirement to the route: the named scheme (from
+// OpenAPIConfig.SecuritySchemes) with optional OAuth2 scopes. Calling it more
+// than once documents alternatives, any one of which is accepted. The route's
+// requirements replace its RestRouter's default; routes with neither inherit
+// OpenAPIConfig.Security.
+//
+// This only documents the requirement; enforce it with an interceptor.
+func (rt *Route) Security(scheme string, scopes ...string) *Route {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	if !rt.securitySet {
+		// The first explicit requirement replaces the router's default.
+		rt.security = nil
+		rt.securitySet = true
+	}
+	rt.security = append(rt.security, SecurityRequirement{scheme: scopes})
+	rt.public = false
+	return rt
+}
+
+// Public marks the route as requiring no authentication, overriding router
+// and global security requirements.
+func (rt *Route) Public() *Route {
+	rt.security = nil
+	rt.public = true
+	return rt
+}
+
+// Security sets the default security requirement for routes registered on
+// this router after the call. Routes can still override it with
+// Route.Security or Route.Public.
+func (r *RestRouter) Security(scheme string, scopes ...string) *RestRouter {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	r.security = append(r.security, SecurityRequirement{scheme: scopes})
+	return r
+}
+
+// operationSecurity returns the operation-level security for a route, or nil
+// when the route inherits the document's global requirements.
+func operationSecurity(rt *Route) []SecurityRequirement {
+	if rt.public {
+		return []SecurityRequirement{}
+	}
+	return rt.security
+}
+
+func securitySchemeObject(s SecurityScheme) map[string]interface{} {
+	obj := map[string]interface{}{"type": s.Type}
+	if s.Description != "" {
+		obj["description"] = s.Description
+	}
+	switch s.Type {
+	case SecurityTypeHTTP:
+		obj["scheme"] = s.Scheme
+		if s.BearerFormat != "" {
+			obj["bearerFormat"] = s.BearerFormat
+		}
+	case SecurityTypeAPIKey:
+		obj["in"] = s.In
+		obj["name"] = s.Name
+	case SecurityTypeOAuth2:
+		flows := map[string]interface{}{}
+		for name, f := range map[string]*OAuthFlow{
+			"implicit":          s.Flows.Implicit,
+			"password":          s.Flows.Password,
+			"clientCredentials": s.Flows.ClientCredentials,
+			"authorizationCode": s.Flows.AuthorizationCode,
+		} {
+			if f != nil {
+				flows[name] = oauthFlowObject(f)
+			}
+		}
+		obj["flows"] = flows
+	case SecurityTypeOpenIDConnect:
+		obj["openIdConnectUrl"] = s.OpenIDConnectURL
+	}
+	return obj
+}
+
+func oauthFlowObject(f *OAuthFlow) map[string]interface{} {
+	scopes := f.Scopes
+	if scopes == nil {
+		scopes = map[string]string{}
+	}
+	obj := map[string]interface{}{"scopes": scopes}
+	if f.AuthorizationURL != "" {
+		obj["authorizationUrl"] = f.AuthorizationURL
+	}
+	if f.TokenURL != "" {
+		obj["tokenUrl"] = f.TokenURL
+	}
+	if f.RefreshURL != "" {
+		obj["refreshUrl"] = f.RefreshURL
+	}
+	return obj
+}
+
+// warnUnknownSchemes logs requirements that name a scheme missing from
+// OpenAPIConfig.SecuritySchemes, which would make the spec invalid.
+func warnUnknownSchemes(cfg OpenAPIConfig, routes []*Route) {
+	if Logger == nil {
+		return
+	}
+	unknown := map[string]bool{}
+	check := func(reqs []SecurityRequirement) {
+		for _, req := range reqs {
+			for name := range req {
+				if _, ok := cfg.SecuritySchemes[name]; !ok {
+					unknown[name] = true
+				}
+			}
+		}
+	}
+	check(cfg.Security)
+	for _, rt := range routes {
+		if !rt.excluded {
+			check(rt.security)
+		}
+	}
+	names := make([]string, 0, len(unknown))
+	for name := range unknown {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		Logger.Warn("OpenAPI security scheme %q is used but not defined in SecuritySchemes", name)
+	}
+}
@@ -0,0 +1,77 @@
+package requiem
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type securedController struct{}
+
+func (securedController) Load(router *Router) {
+	r := router.NewRestRouter("/orders").Security("bearer")
+	r.Get("/", func(ctx HTTPContext) {}).Summary("List orders")
+	r.Get("/public", func(ctx HTTPContext) {}).Public()
+	r.Post("/", func(ctx HTTPContext) {}, nil).
+		Security("oauth", "orders:write").
+		Security("apiKey")
+
+	router.NewRestRouter("/status").Get("/", func(ctx HTTPContext) {})
+}
+
+func securedSpec(t *testing.T) map[string]interface{} {
+	t.Helper()
+	router := newRouter("/api", nil, []IHttpController{securedController{}})
+	cfg := OpenAPIConfig{
+		Title:   "T",
+		Version: "1",
+		SecuritySchemes: map[string]SecurityScheme{
+			"bearer": BearerAuth("JWT"),
+			"apiKey": APIKeyAuth("header", "X-API-Key"),
+			"basic":  BasicAuth(),
+			"oauth": OAuth2Auth(OAuthFlows{ClientCredentials: &OAuthFlow{
+				TokenURL: "https://auth.example.com/token",
+				Scopes:   map[string]string{"orders:write": "Create orders"},
+			}}),
+		},
+		Security: []SecurityRequirement{{"basic": {}}},
+	}
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buildDoc(cfg, router.routes), &spec))
+	return spec
+}
+
+func TestSecurity_SchemesInComponents(t *testing.T) {
+	spec := securedSpec(t)
+	schemes := spec["components"].(map[string]interface{})["securitySchemes"].(map[string]interface{})
+
+	assert.Equal(t, map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"}, schemes["bearer"])
+	assert.Equal(t, map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"}, schemes["apiKey"])
+	assert.Equal(t, map[string]interface{}{"type": "http", "scheme": "basic"}, schemes["basic"])
+
+	flow := schemes["oauth"].(map[string]interface{})["flows"].(map[string]interface{})["clientCredentials"].(map[string]interface{})
+	assert.Equal(t, "https://auth.example.com/token", flow["tokenUrl"])
+	assert.Contains(t, flow["scopes"], "orders:write")
+
+	assert.Equal(t, []interface{}{map[string]interface{}{"basic": []interface{}{}}}, spec["security"])
+}
+
+func TestSecurity_OperationRequirements(t *testing.T) {
+	paths := securedSpec(t)["paths"].(map[string]interface{})
+	op := func(path, method string) map[string]interface{} {
+		return paths[path].(map[string]interface{})[method].(map[string]interface{})
+	}
+
+	assert.Equal(t, []interface{}{map[string]interface{}{"bearer": []interface{}{}}}, op("/orders/", "get")["security"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"oauth": []interface{}{"orders:write"}},
+		map[string]interface{}{"apiKey": []interface{}{}},
+	}, op("/orders/", "post")["security"])
+
+	// Public routes clear inherited requirements explicitly.
+	assert.Equal(t, []interface{}{}, op("/orders/public", "get")["security"])
+
+	// Routes on other routers inherit the document-wide default.
+	assert.NotContains(t, op("/status/", "get"), "security")
+}
@@ -3,6 +3,7 @@ package requiem
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -22,12 +23,30 @@ type Server struct {
 	healthcheckEnabled bool
 	openapiEnabled     bool
 	mcpEnabled         bool
+	tenancyEnabled     bool
+	auditEnabled       bool
+	mockEnabled        bool
 	db                 *gorm.DB
 	controllers        []IHttpController
+	models             []interface{}
+	fixtures           []func() error
+	webhooks           *webhookController
+
+	// PurgeInterval is how often the trash addon purges soft-deleted rows
+	// past their retention (see TrashRoutes.Retain). Defaults to one hour.
+	PurgeInterval time.Duration
 }
 
+// UsePostgresDB connects to Postgres using the DB_* environment variables.
 func (s *Server) UsePostgresDB(debugMode bool) {
-	s.db = newPostgresDBConnection(debugMode)
+	s.db = newPostgresDBConnection(loadDBConfig(), debugMode)
+}
+
+// UsePostgresDBWithConfig connects to Postgres using an explicit config. Read
+// replicas listed in cfg.ReplicaHosts serve reads from GET handlers and
+// read-only routes through HTTPContext.DB; everything else uses the primary.
+func (s *Server) UsePostgresDBWithConfig(cfg DBConfig, debugMode bool) {
+	s.db = newPostgresDBConnection(cfg, debugMode)
 }
 
 func (s *Server) UseInMemoryDB(debugMode bool) {
@@ -43,12 +62,12 @@ func (s *Server) UseHealthcheck() {
 
 func (s *Server) UseOpenAPI(cfg OpenAPIConfig) {
 	if !s.openapiEnabled {
-		s.controllers = append(s.controllers, &openapiController{cfg: cfg})
+		s.controllers = append(s.controllers, &openapiController{cfg: cfg, violations: &responseViolations{}})
 		s.openapiEnabled = true
 	}
 }
 
-// GetOpenAPISpec returns the OpenAPI 3.0 spec for the server's routes as JSON
+// GetOpenAPISpec returns the OpenAPI spec for the server's routes as JSON
 // bytes without starting an HTTP listener. Controllers are loaded into a
 // throwaway router solely to register their routes. Returns nil if UseOpenAPI
 // was never called.
@@ -59,12 +78,55 @@ func (s *Server) GetOpenAPISpec() []byte {
 	r := newRouter(s.BasePath, s.db, s.controllers)
 	for _, c := range s.controllers {
 		if oc, ok := c.(*openapiController); ok {
-			return buildDoc(oc.cfg, r.routes)
+			return buildDoc(oc.cfg, r.routes, r.webhooks...)
 		}
 	}
 	return nil
 }
 
+// ResponseViolations returns the violations recorded in
+// ResponseValidationRecord mode since the last ResetResponseViolations.
+func (s *Server) ResponseViolations() []ResponseViolation {
+	return s.responseViolations().all()
+}
+
+// ResetResponseViolations clears the recorded response violations.
+func (s *Server) ResetResponseViolations() {
+	s.responseViolations().reset()
+}
+
+// AssertNoResponseViolations fails t for each recorded response violation,
+// then clears them. Pass a *testing.T.
+func (s *Server) AssertNoResponseViolations(t testingT) {
+	t.Helper()
+	s.responseViolations().assertNone(t)
+}
+
+// responseViolations returns the OpenAPI addon's record of response
+// violations, or an empty one if UseOpenAPI was never called.
+func (s *Server) responseViolations() *responseViolations {
+	for _, c := range s.controllers {
+		if oc, ok := c.(*openapiController); ok {
+			return oc.violations
+		}
+	}
+	return &responseViolations{}
+}
+
+// GetOpenAPISpecYAML is GetOpenAPISpec rendered as YAML.
+func (s *Server) GetOpenAPISpecYAML() []byte {
+	spec := s.GetOpenAPISpec()
+	if spec == nil {
+		return nil
+	}
+	b, err := specToYAML(spec)
+	if err != nil {
+		Logger.Error("Could not render OpenAPI spec as YAML: %s", err)
+		return nil
+	}
+	return b
+}
+
 // UseMCP enables an optional Model Context Protocol (MCP) endpoint that exposes
 // the server's registered REST routes as MCP tools. It is purely additive: when
 // called, an mcpController is appended to the server's controllers and serves a
@@ -78,10 +140,22 @@ func (s *Server) UseMCP(cfg MCPConfig) {
 	}
 }
 
+// UseMock serves every registered route from its declared Returns instead of
+// its handler, so consumers can develop against the API contract before the
+// handlers (or a database) exist. Responses use the route's examples where
+// declared and data generated from the response schema otherwise.
+func (s *Server) UseMock(cfg MockConfig) {
+	if !s.mockEnabled {
+		s.controllers = append(s.controllers, &mockController{cfg: cfg})
+		s.mockEnabled = true
+	}
+}
+
 func (s *Server) AutoMigrate(models ...interface{}) {
 	for idx := range models {
 		s.db.AutoMigrate(models[idx])
 	}
+	s.models = append(s.models, models...)
 }
 
 // Start initializes the API and starts running on the specified port
@@ -90,15 +164,32 @@ func (s *Server) Start() {
 	if s.db != nil {
 		sqlDB, _ := s.db.DB()
 		defer sqlDB.Close()
+
+		if rr := replicaRouterOf(s.db); rr != nil {
+			stop := rr.monitor()
+			defer stop()
+		}
+
+		for _, load := range s.fixtures {
+			if err := load(); err != nil {
+				Logger.Fatal("Could not load fixtures: %s", err)
+			}
+		}
 	}
 
 	// Create API router and load controllers
 	r := newRouter(s.BasePath, s.db, s.controllers)
 	r.printRoutes()
 
+	stopPurge := r.startPurge(s.PurgeInterval)
+	defer stopPurge()
+
+	stopWebhooks := r.startWebhooks()
+	defer stopWebhooks()
+
 	// Create HTTP server using API router
 	srv := &http.Server{
-		Handler: r.MuxRouter,
+		Handler: r,
 		Addr:    fmt.Sprintf(":%d", s.Port),
 	}
 
@@ -0,0 +1,320 @@
+package requiem
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const defaultPurgeInterval = time.Hour
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// TrashRoutes are the routes registered by RestRouter.Trash, exposed so their
+// OpenAPI/MCP metadata can be refined further.
+type TrashRoutes struct {
+	// Delete soft-deletes a row: DELETE {prefix}/{id}.
+	Delete *Route
+	// Restore undeletes a row: POST {prefix}/{id}/restore.
+	Restore *Route
+	// List returns soft-deleted rows: GET {prefix}/trash.
+	List *Route
+
+	router *Router
+	model  reflect.Type
+	// retained is the retention note Retain added to the descriptions.
+	retained string
+}
+
+// trashTarget is a soft-deletable model with a retention period, purged by the
+// server's background job.
+type trashTarget struct {
+	model     reflect.Type
+	retention time.Duration
+}
+
+// Trash registers trash conventions for a soft-deletable GORM model (one with a
+// gorm.DeletedAt field) on this router:
+//
+//	GET    /trash         lists soft-deleted rows (limit/offset query params)
+//	DELETE /{id}          soft-deletes a row
+//	POST   /{id}/restore  restores a soft-deleted row
+//
+// Trash replaces RestRouter.Delete for the model: its DELETE /{id} is a soft
+// delete, and registering another DELETE /{id} on the router, before or after
+// and with or without a pattern such as /{id:[0-9]+}, is fatal. Handlers use HTTPContext.DB, so tenancy and replica routing apply.
+// Because mux matches routes in registration order, call Trash before
+// registering other GET /{id} routes on the same router.
+func (r *RestRouter) Trash(model interface{}, interceptors ...HTTPInterceptor) *TrashRoutes {
+	t := reflect.TypeOf(model)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !isSoftDeletable(t) {
+		Logger.Fatal("%s => Trash requires a gorm.DeletedAt field", t.Name())
+	}
+
+	for _, rt := range r.parent.routes {
+		if rt.method == http.MethodDelete && stripPathRegex(rt.path) == r.prefix+"/{id}" {
+			Logger.Fatal("[DELETE] %s => Trash replaces the Delete route registered here; remove it", rt.path)
+		}
+	}
+
+	name := t.Name()
+	list := reflect.MakeSlice(reflect.SliceOf(t), 0, 0).Interface()
+	tr := &TrashRoutes{router: r.parent, model: t}
+
+	tr.List = r.Get("/trash", func(ctx HTTPContext) {
+		listTrash(ctx, t)
+	}, interceptors...).
+		Summary(fmt.Sprintf("List deleted %s", name)).
+		Description(fmt.Sprintf("Lists %s rows that were soft-deleted and can still be restored.", name)).
+		Query("limit", "integer", false, "Max items (default 100)").
+		Query("offset", "integer", false, "Items to skip").
+		Returns(http.StatusOK, list, fmt.Sprintf("Deleted %s rows", name)).
+		ReadOnly()
+
+	tr.Delete = r.Delete("/{id}", func(ctx HTTPContext) {
+		softDelete(ctx, t)
+	}, nil, interceptors...).
+		Summary(fmt.Sprintf("Delete %s", name)).
+		Description(fmt.Sprintf("Moves the %s to the trash. It can be restored until it is purged.", name)).
+		Param("id", "string", fmt.Sprintf("%s identifier", name)).
+		Returns(http.StatusNoContent, nil, "Deleted").
+		Returns(http.StatusNotFound, nil, "Not found")
+
+	tr.Restore = r.Post("/{id}/restore", func(ctx HTTPContext) {
+		restoreTrash(ctx, t)
+	}, nil, interceptors...).
+		Summary(fmt.Sprintf("Restore %s", name)).
+		Description(fmt.Sprintf("Restores a soft-deleted %s.", name)).
+		Param("id", "string", fmt.Sprintf("%s identifier", name)).
+		Returns(http.StatusNoContent, nil, "Restored").
+		Returns(http.StatusNotFound, nil, "Not in trash")
+
+	r.trash = tr
+	return tr
+}
+
+// Retain sets how long soft-deleted rows are kept before the server's purge
+// job deletes them permanently (see Server.PurgeInterval). The retention is
+// also noted on the routes' descriptions. Calling it again replaces the
+// retention.
+func (tr *TrashRoutes) Retain(retention time.Duration) *TrashRoutes {
+	target := trashTarget{model: tr.model, retention: retention}
+	replaced := false
+	for i, t := range tr.router.trash {
+		if t.model == tr.model {
+			tr.router.trash[i], replaced = target, true
+		}
+	}
+	if !replaced {
+		tr.router.trash = append(tr.router.trash, target)
+	}
+
+	note := fmt.Sprintf(" Deleted rows are purged after %s.", formatRetention(retention))
+	for _, rt := range []*Route{tr.Delete, tr.List} {
+		rt.description = strings.TrimSuffix(rt.description, tr.retained) + note
+	}
+	tr.retained = note
+	return tr
+}
+
+// formatRetention renders a retention in the largest whole unit that fits,
+// e.g. "30 days" rather than time.Duration's "720h0m0s".
+func formatRetention(d time.Duration) string {
+	for _, u := range []struct {
+		size time.Duration
+		name string
+	}{{24 * time.Hour, "day"}, {time.Hour, "hour"}, {time.Minute, "minute"}, {time.Second, "second"}} {
+		if d >= u.size && d%u.size == 0 {
+			n := int64(d / u.size)
+			if n == 1 {
+				return "1 " + u.name
+			}
+			return fmt.Sprintf("%d %ss", n, u.name)
+		}
+	}
+	return d.String()
+}
+
+func isSoftDeletable(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type == deletedAtType {
+			return true
+		}
+		if f.Anonymous && isSoftDeletable(f.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// deletedAtColumn returns the DB column of the model's gorm.DeletedAt field.
+func deletedAtColumn(s *schema.Schema) string {
+	for _, f := range s.Fields {
+		if f.FieldType == deletedAtType {
+			return f.DBName
+		}
+	}
+	return "deleted_at"
+}
+
+func parseSchema(db *gorm.DB, t reflect.Type) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(reflect.New(t).Interface()); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+func listTrash(ctx HTTPContext, t reflect.Type) {
+	db := ctx.DB()
+	if db == nil {
+		ctx.SendStatus(http.StatusInternalServerError)
+		return
+	}
+	s, err := parseSchema(db, t)
+	if err != nil {
+		ctx.SendStatus(http.StatusInternalServerError)
+		return
+	}
+
+	limit, offset := 100, 0
+	if v := ctx.GetQueryParam("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			ctx.SendStatus(http.StatusBadRequest)
+			return
+		}
+	}
+	if v := ctx.GetQueryParam("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			ctx.SendStatus(http.StatusBadRequest)
+			return
+		}
+	}
+
+	rows := reflect.New(reflect.SliceOf(t))
+	err = db.Unscoped().
+		Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: deletedAtColumn(s)}, Value: nil}).
+		Limit(limit).Offset(offset).
+		Find(rows.Interface()).Error
+	if err != nil {
+		ctx.SendStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.SendJSON(rows.Elem().Interface())
+}
+
+func softDelete(ctx HTTPContext, t reflect.Type) {
+	db := ctx.DB()
+	if db == nil {
+		ctx.SendStatus(http.StatusInternalServerError)
+		return
+	}
+	res := db.Where(clause.Eq{Column: clause.PrimaryColumn, Value: ctx.GetParam("id")}).
+		Delete(reflect.New(t).Interface())
+	switch {
+	case res.Error != nil:
+		ctx.SendStatus(http.StatusInternalServerError)
+	case res.RowsAffected == 0:
+		ctx.SendStatus(http.StatusNotFound)
+	default:
+		ctx.SendStatus(http.StatusNoContent)
+	}
+}
+
+func restoreTrash(ctx HTTPContext, t reflect.Type) {
+	db := ctx.DB()
+	if db == nil {
+		ctx.SendStatus(http.StatusInternalServerError)
+		return
+	}
+	s, err := parseSchema(db, t)
+	if err != nil {
+		ctx.SendStatus(http.StatusInternalServerError)
+		return
+	}
+	col := deletedAtColumn(s)
+	res := db.Unscoped().Model(reflect.New(t).Interface()).
+		Where(clause.Eq{Column: clause.PrimaryColumn, Value: ctx.GetParam("id")}).
+		Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: col}, Value: nil}).
+		Update(col, nil)
+	switch {
+	case res.Error != nil:
+		ctx.SendStatus(http.StatusInternalServerError)
+	case res.RowsAffected == 0:
+		ctx.SendStatus(http.StatusNotFound)
+	default:
+		ctx.SendStatus(http.StatusNoContent)
+	}
+}
+
+// PurgeTrash permanently deletes rows of the given soft-deletable models that
+// were deleted more than retention ago, returning the number of rows removed.
+// It runs unscoped, across every tenant.
+func PurgeTrash(db *gorm.DB, retention time.Duration, models ...interface{}) (int64, error) {
+	cutoff := time.Now().Add(-retention)
+	var purged int64
+	for _, m := range models {
+		t := reflect.TypeOf(m)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		s, err := parseSchema(db, t)
+		if err != nil {
+			return purged, err
+		}
+		res := db.Unscoped().
+			Where(clause.Lt{Column: clause.Column{Table: clause.CurrentTable, Name: deletedAtColumn(s)}, Value: cutoff}).
+			Delete(reflect.New(t).Interface())
+		if res.Error != nil {
+			return purged, res.Error
+		}
+		purged += res.RowsAffected
+	}
+	return purged, nil
+}
+
+// startPurge runs PurgeTrash for every model registered with TrashRoutes.Retain
+// every interval until the returned stop function is called.
+func (r *Router) startPurge(interval time.Duration) func() {
+	if r.DB == nil || len(r.trash) == 0 {
+		return func() {}
+	}
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				for _, target := range r.trash {
+					n, err := PurgeTrash(r.DB, target.retention, reflect.New(target.model).Interface())
+					if err != nil {
+						Logger.Error("Could not purge %s: %s", target.model.Name(), err)
+					} else if n > 0 {
+						Logger.Info("Purged %d deleted %s rows", n, target.model.Name())
+					}
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,117 @@
+package requiem
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type Gadget struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+}
+
+type gadgetController struct{}
+
+func (c gadgetController) Load(router *Router) {
+	r := router.NewRestRouter("/gadgets")
+	r.Trash(&Gadget{}).Retain(30 * 24 * time.Hour)
+	r.Get("/{id}", func(ctx HTTPContext) {
+		var g Gadget
+		if ctx.DB().First(&g, ctx.GetParam("id")).Error != nil {
+			ctx.SendStatus(http.StatusNotFound)
+			return
+		}
+		ctx.SendJSON(g)
+	})
+}
+
+func gadgetRouter(t *testing.T) *Router {
+	t.Helper()
+	db := testDB(t, "", &Gadget{})
+	db.Create(&Gadget{Name: "sprocket"})
+	return newRouter(defaultBasePath, db, []IHttpController{gadgetController{}})
+}
+
+func TestTrash_DeleteListRestore(t *testing.T) {
+	r := gadgetRouter(t)
+
+	assert.Equal(t, http.StatusNoContent, serve(r, http.MethodDelete, "/api/gadgets/1", "", nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodDelete, "/api/gadgets/1", "", nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/api/gadgets/1", "", nil).Code)
+
+	rec := serve(r, http.MethodGet, "/api/gadgets/trash", "", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var trashed []Gadget
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &trashed))
+	assert.Len(t, trashed, 1)
+	assert.True(t, trashed[0].DeletedAt.Valid)
+
+	assert.Equal(t, http.StatusNoContent, serve(r, http.MethodPost, "/api/gadgets/1/restore", "", nil).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPost, "/api/gadgets/1/restore", "", nil).Code)
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/api/gadgets/1", "", nil).Code)
+}
+
+func TestTrash_PurgeRemovesExpiredRows(t *testing.T) {
+	r := gadgetRouter(t)
+	r.DB.Create(&Gadget{Name: "recent"})
+	r.DB.Delete(&Gadget{}, 1)
+	r.DB.Delete(&Gadget{}, 2)
+	r.DB.Unscoped().Model(&Gadget{}).Where("id = ?", 1).Update("deleted_at", time.Now().Add(-48*time.Hour))
+
+	n, err := PurgeTrash(r.DB, 24*time.Hour, &Gadget{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	var remaining []Gadget
+	r.DB.Unscoped().Find(&remaining)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "recent", remaining[0].Name)
+}
+
+func TestTrash_DocumentedInSpec(t *testing.T) {
+	r := gadgetRouter(t)
+
+	var spec map[string]interface{}
+	json.Unmarshal(buildDoc(OpenAPIConfig{Title: "T", Version: "1"}, r.routes), &spec)
+	paths := spec["paths"].(map[string]interface{})
+
+	assert.Contains(t, paths["/gadgets/trash"], "get")
+	assert.Contains(t, paths["/gadgets/{id}/restore"], "post")
+	del := paths["/gadgets/{id}"].(map[string]interface{})["delete"].(map[string]interface{})
+	assert.Contains(t, del["description"], "purged after 30 days")
+
+	gadget := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})["Gadget"].(map[string]interface{})
+	deletedAt := gadget["properties"].(map[string]interface{})["deleted_at"].(map[string]interface{})
+	assert.Equal(t, "date-time", deletedAt["format"])
+	assert.Equal(t, true, deletedAt["nullable"])
+}
+
+type retainTwiceController struct{ routes *TrashRoutes }
+
+func (c *retainTwiceController) Load(router *Router) {
+	c.routes = router.NewRestRouter("/gadgets").Trash(&Gadget{}).Retain(time.Hour).Retain(48 * time.Hour)
+}
+
+func TestTrash_RetainReplacesRetention(t *testing.T) {
+	c := &retainTwiceController{}
+	r := newRouter(defaultBasePath, nil, []IHttpController{c})
+
+	assert.Equal(t, []trashTarget{{model: reflect.TypeOf(Gadget{}), retention: 48 * time.Hour}}, r.trash)
+	assert.True(t, strings.HasSuffix(c.routes.Delete.description, "It can be restored until it is purged. Deleted rows are purged after 2 days."))
+	assert.NotContains(t, c.routes.List.description, "1 hour")
+}
+
+func TestFormatRetention(t *testing.T) {
+	assert.Equal(t, "1 day", formatRetention(24*time.Hour))
+	assert.Equal(t, "36 hours", formatRetention(36*time.Hour))
+	assert.Equal(t, "90 minutes", formatRetention(90*time.Minute))
+	assert.Equal(t, "1.5s", formatRetention(1500*time.Millisecond))
+}
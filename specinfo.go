@@ -0,0 +1,190 @@
+package requiem
+
+import (
+	"sort"
+	"strings"
+)
+
+// Contact is the API's contact information, emitted as info.contact.
+type Contact struct {
+	Name  string
+	URL   string
+	Email string
+}
+
+// License is the API's license, emitted as info.license. Identifier is an
+// SPDX expression and is only emitted for OpenAPI 3.1, where it is an
+// alternative to URL.
+type License struct {
+	Name       string
+	URL        string
+	Identifier string
+}
+
+// ExternalDocs links to documentation outside the spec.
+type ExternalDocs struct {
+	URL         string
+	Description string
+}
+
+// Tag describes a tag used by Route.Tags. Tags are listed in the spec in the
+// order given, which docs pages use to order their sections; tags used by
+// routes but not declared follow in alphabetical order.
+type Tag struct {
+	Name         string
+	Description  string
+	ExternalDocs *ExternalDocs
+}
+
+// APIServer is a server the API is reachable at, with an optional description
+// and URL template variables, e.g. "https://{region}.example.com/{basePath}".
+type APIServer struct {
+	URL         string
+	Description string
+	Variables   map[string]ServerVariable
+}
+
+// ServerVariable is a substitution for a {name} in an APIServer URL.
+type ServerVariable struct {
+	Default     string
+	Enum        []string
+	Description string
+}
+
+// ExternalDocs links the operation to documentation outside the spec.
+func (rt *Route) ExternalDocs(url, description string) *Route {
+	rt.externalDocs = &ExternalDocs{URL: url, Description: description}
+	return rt
+}
+
+func infoObject(cfg OpenAPIConfig) map[string]interface{} {
+	info := map[string]interface{}{
+		"title":   cfg.Title,
+		"version": cfg.Version,
+	}
+	if cfg.Description != "" {
+		info["description"] = cfg.Description
+	}
+	if cfg.TermsOfService != "" {
+		info["termsOfService"] = cfg.TermsOfService
+	}
+	if c := cfg.Contact; c != nil {
+		contact := map[string]interface{}{}
+		for k, v := range map[string]string{"name": c.Name, "url": c.URL, "email": c.Email} {
+			if v != "" {
+				contact[k] = v
+			}
+		}
+		info["contact"] = contact
+	}
+	if l := cfg.License; l != nil {
+		license := map[string]interface{}{"name": l.Name}
+		if l.URL != "" {
+			license["url"] = l.URL
+		}
+		if l.Identifier != "" && cfg.is31() {
+			license["identifier"] = l.Identifier
+		}
+		info["license"] = license
+	}
+	return info
+}
+
+func externalDocsObject(d *ExternalDocs) map[string]interface{} {
+	obj := map[string]interface{}{"url": d.URL}
+	if d.Description != "" {
+		obj["description"] = d.Description
+	}
+	return obj
+}
+
+// serverObjects lists cfg.Servers followed by cfg.ServerDetails.
+func serverObjects(cfg OpenAPIConfig) []map[string]interface{} {
+	servers := make([]map[string]interface{}, 0, len(cfg.Servers)+len(cfg.ServerDetails))
+	for _, u := range cfg.Servers {
+		servers = append(servers, map[string]interface{}{"url": u})
+	}
+	for _, s := range cfg.ServerDetails {
+		obj := map[string]interface{}{"url": s.URL}
+		if s.Description != "" {
+			obj["description"] = s.Description
+		}
+		if len(s.Variables) > 0 {
+			vars := make(map[string]interface{}, len(s.Variables))
+			for name, v := range s.Variables {
+				vo := map[string]interface{}{"default": v.Default}
+				if len(v.Enum) > 0 {
+					vo["enum"] = v.Enum
+				}
+				if v.Description != "" {
+					vo["description"] = v.Description
+				}
+				vars[name] = vo
+			}
+			obj["variables"] = vars
+		}
+		servers = append(servers, obj)
+	}
+	return servers
+}
+
+// serverURLs returns every URL the configured servers can expand to, trying
+// each enum value (or the default) of their variables.
+func serverURLs(cfg OpenAPIConfig) []string {
+	urls := append([]string(nil), cfg.Servers...)
+	for _, s := range cfg.ServerDetails {
+		expanded := []string{s.URL}
+		for name, v := range s.Variables {
+			values := v.Enum
+			if len(values) == 0 {
+				values = []string{v.Default}
+			}
+			next := make([]string, 0, len(expanded)*len(values))
+			for _, u := range expanded {
+				for _, val := range values {
+					next = append(next, strings.ReplaceAll(u, "{"+name+"}", val))
+				}
+			}
+			expanded = next
+		}
+		urls = append(urls, expanded...)
+	}
+	return urls
+}
+
+// tagObjects lists the declared tags in order, then any other tag a route uses.
+func tagObjects(cfg OpenAPIConfig, routes []*Route) []map[string]interface{} {
+	if len(cfg.Tags) == 0 {
+		return nil
+	}
+	declared := map[string]bool{}
+	tags := make([]map[string]interface{}, 0, len(cfg.Tags))
+	for _, t := range cfg.Tags {
+		declared[t.Name] = true
+		obj := map[string]interface{}{"name": t.Name}
+		if t.Description != "" {
+			obj["description"] = t.Description
+		}
+		if t.ExternalDocs != nil {
+			obj["externalDocs"] = externalDocsObject(t.ExternalDocs)
+		}
+		tags = append(tags, obj)
+	}
+	var undeclared []string
+	for _, rt := range routes {
+		if rt.excluded {
+			continue
+		}
+		for _, t := range rt.tags {
+			if !declared[t] {
+				declared[t] = true
+				undeclared = append(undeclared, t)
+			}
+		}
+	}
+	sort.Strings(undeclared)
+	for _, t := range undeclared {
+		tags = append(tags, map[string]interface{}{"name": t})
+	}
+	return tags
+}
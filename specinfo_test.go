@@ -0,0 +1,84 @@
+package requiem
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type catalogController struct{}
+
+func (catalogController) Load(router *Router) {
+	r := router.NewRestRouter("/catalog")
+	r.Get("/", func(ctx HTTPContext) {}).
+		Tags("products", "search").
+		ExternalDocs("https://docs.example.com/search", "Search syntax")
+	r.Get("/{id}", func(ctx HTTPContext) {}).Tags("products", "archive")
+}
+
+func TestSpecInfo_Metadata(t *testing.T) {
+	router := newRouter("/api", nil, []IHttpController{catalogController{}})
+	cfg := OpenAPIConfig{
+		Title:          "Catalog",
+		Version:        "1",
+		TermsOfService: "https://example.com/terms",
+		Contact:        &Contact{Name: "API team", Email: "api@example.com"},
+		License:        &License{Name: "Apache 2.0", Identifier: "Apache-2.0"},
+		Servers:        []string{"https://catalog.example.com"},
+		ServerDetails: []APIServer{{
+			URL:         "https://{region}.example.com/api",
+			Description: "Regional",
+			Variables:   map[string]ServerVariable{"region": {Default: "eu", Enum: []string{"eu", "us"}}},
+		}},
+		Tags: []Tag{
+			{Name: "search", Description: "Full-text search"},
+			{Name: "products", ExternalDocs: &ExternalDocs{URL: "https://docs.example.com/products"}},
+		},
+		ExternalDocs: &ExternalDocs{URL: "https://docs.example.com", Description: "Guides"},
+	}
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buildDoc(cfg, router.routes), &spec))
+
+	info := spec["info"].(map[string]interface{})
+	assert.Equal(t, "https://example.com/terms", info["termsOfService"])
+	assert.Equal(t, map[string]interface{}{"name": "API team", "email": "api@example.com"}, info["contact"])
+	assert.Equal(t, map[string]interface{}{"name": "Apache 2.0"}, info["license"])
+
+	servers := spec["servers"].([]interface{})
+	assert.Len(t, servers, 2)
+	assert.Equal(t, map[string]interface{}{
+		"url":         "https://{region}.example.com/api",
+		"description": "Regional",
+		"variables": map[string]interface{}{
+			"region": map[string]interface{}{"default": "eu", "enum": []interface{}{"eu", "us"}},
+		},
+	}, servers[1])
+
+	var names []interface{}
+	for _, tag := range spec["tags"].([]interface{}) {
+		names = append(names, tag.(map[string]interface{})["name"])
+	}
+	assert.Equal(t, []interface{}{"search", "products", "archive"}, names)
+	assert.Equal(t, "Full-text search", spec["tags"].([]interface{})[0].(map[string]interface{})["description"])
+
+	assert.Equal(t, map[string]interface{}{"url": "https://docs.example.com", "description": "Guides"}, spec["externalDocs"])
+	op := spec["paths"].(map[string]interface{})["/catalog/"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"url": "https://docs.example.com/search", "description": "Search syntax"}, op["externalDocs"])
+
+	cfg.OpenAPIVersion = OpenAPIVersion31
+	spec = nil
+	assert.NoError(t, json.Unmarshal(buildDoc(cfg, router.routes), &spec))
+	assert.Equal(t, "Apache-2.0", spec["info"].(map[string]interface{})["license"].(map[string]interface{})["identifier"])
+}
+
+func TestSpecInfo_ServerURLs(t *testing.T) {
+	urls := serverURLs(OpenAPIConfig{
+		Servers: []string{"https://a.example.com"},
+		ServerDetails: []APIServer{{
+			URL:       "https://{region}.example.com:{port}",
+			Variables: map[string]ServerVariable{"region": {Default: "eu", Enum: []string{"eu", "us"}}, "port": {Default: "443"}},
+		}},
+	})
+	assert.ElementsMatch(t, []string{"https://a.example.com", "https://eu.example.com:443", "https://us.example.com:443"}, urls)
+}
@@ -0,0 +1,373 @@
+package requiem
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultTenantColumn       = "tenant_id"
+	defaultTenantSchemaPrefix = "tenant_"
+
+	// tenantSettingKey is the gorm.DB setting carrying the active tenantScope.
+	// It is read by the callbacks registered in registerTenantCallbacks.
+	tenantSettingKey = "requiem:tenant"
+)
+
+var (
+	// ErrNoTenant is returned by queries on HTTPContext.DB when tenancy is
+	// enabled but no tenant could be resolved for the request. Tenancy fails
+	// closed: an unscoped query is never issued on a tenant-aware router.
+	ErrNoTenant = errors.New("requiem: no tenant resolved for request")
+
+	// ErrTenantMismatch is returned when a row being inserted or updated
+	// carries a tenant ID different from the one the DB handle is scoped to.
+	ErrTenantMismatch = errors.New("requiem: row belongs to a different tenant")
+
+	// ErrTenantUnscoped is returned by statements on a tenant-scoped DB handle
+	// that can't be scoped because they have no model: raw SQL, or Table()
+	// with a map destination. Run those on Router.DB with an explicit filter.
+	ErrTenantUnscoped = errors.New("requiem: cannot scope a statement without a model to a tenant")
+
+	// ErrInvalidTenant is returned in SchemaPerTenant mode for tenant IDs that
+	// aren't usable as a schema name as they are. IDs are never rewritten, so
+	// two tenants can't end up sharing a schema.
+	ErrInvalidTenant = errors.New("requiem: tenant ID is not a valid schema name")
+)
+
+// TenantConfig configures the optional multi-tenancy addon. The tenant for a
+// request is resolved from, in order: the context attribute named by Attribute
+// (set by an interceptor), the request header named by Header, and finally the
+// leftmost label of the request host when Subdomain is true.
+type TenantConfig struct {
+	// Attribute names a context attribute holding the tenant ID as a string,
+	// typically set by an auth interceptor from the caller's claims.
+	Attribute string
+	// Header names a request header carrying the tenant ID (e.g. "X-Tenant-ID").
+	Header string
+	// Subdomain resolves the tenant from the host, e.g. "acme.example.com" -> "acme".
+	Subdomain bool
+	// Column is the tenant column used to filter queries and stamp inserts.
+	// Defaults to "tenant_id". Models without the column are not scoped.
+	Column string
+	// SchemaPerTenant switches from row-level filtering to one Postgres schema
+	// per tenant: every statement's table is qualified with SchemaPrefix + tenant
+	// instead of being filtered on Column.
+	SchemaPerTenant bool
+	// SchemaPrefix prefixes tenant schema names. Defaults to "tenant_".
+	SchemaPrefix string
+}
+
+// tenantScope is the per-statement tenant state stored under tenantSettingKey.
+type tenantScope struct {
+	id     string
+	column string
+	schema string
+	err    error
+}
+
+func (cfg TenantConfig) column() string {
+	if cfg.Column == "" {
+		return defaultTenantColumn
+	}
+	return cfg.Column
+}
+
+var tenantSchemaID = regexp.MustCompile(`^[a-z0-9][a-z0-9_]*$`)
+
+// maxSchemaNameLength is Postgres' identifier length limit; longer names are
+// silently truncated, which could map two tenants to one schema.
+const maxSchemaNameLength = 63
+
+// SchemaName returns the Postgres schema holding the given tenant's tables in
+// SchemaPerTenant mode. Tenant IDs must be lowercase letters, digits and
+// underscores; anything else fails with ErrInvalidTenant.
+func (cfg TenantConfig) SchemaName(tenant string) (string, error) {
+	prefix := cfg.SchemaPrefix
+	if prefix == "" {
+		prefix = defaultTenantSchemaPrefix
+	}
+	if !tenantSchemaID.MatchString(tenant) || len(prefix)+len(tenant) > maxSchemaNameLength {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTenant, tenant)
+	}
+	return prefix + tenant, nil
+}
+
+// Scope returns a GORM scope restricting a DB handle to the given tenant. It is
+// what HTTPContext.DB applies for request handlers, and can be used directly on
+// Router.DB for work outside a request (background jobs, migrations, etc.):
+//
+//	router.DB.Scopes(cfg.Scope("acme")).Find(&widgets)
+func (cfg TenantConfig) Scope(tenant string) func(*gorm.DB) *gorm.DB {
+	ts := &tenantScope{id: tenant, column: cfg.column()}
+	if cfg.SchemaPerTenant && tenant != "" {
+		ts.schema, ts.err = cfg.SchemaName(tenant)
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Set(tenantSettingKey, ts)
+	}
+}
+
+// resolve returns the tenant for the request, or "" if none could be found.
+func (cfg TenantConfig) resolve(ctx HTTPContext) string {
+	if cfg.Attribute != "" {
+		if s, ok := ctx.GetAttribute(cfg.Attribute).(string); ok && s != "" {
+			return s
+		}
+	}
+	if cfg.Header != "" {
+		if s := ctx.Request.Header.Get(cfg.Header); s != "" {
+			return s
+		}
+	}
+	if cfg.Subdomain {
+		return subdomainOf(ctx.Request.Host)
+	}
+	return ""
+}
+
+// subdomainOf returns the leftmost label of a host with at least three labels
+// ("acme.example.com" -> "acme"). IP addresses and bare domains yield "".
+func subdomainOf(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 {
+		return ""
+	}
+	return labels[0]
+}
+
+// UseTenancy enables automatic tenant scoping of HTTPContext.DB. Handlers that
+// use ctx.DB() get a handle whose queries, updates and deletes are filtered to
+// the request's tenant and whose inserts are stamped with it; updates can't
+// change a row's tenant. Models without the tenant column, such as shared
+// lookup tables, are not scoped at all and stay visible to every tenant.
+// Router.DB stays unscoped for cross-tenant work.
+func (s *Server) UseTenancy(cfg TenantConfig) {
+	if !s.tenancyEnabled {
+		s.controllers = append(s.controllers, &tenantController{cfg: cfg})
+		s.tenancyEnabled = true
+	}
+}
+
+type tenantController struct {
+	cfg TenantConfig
+}
+
+func (c *tenantController) Load(router *Router) {
+	router.tenancy = &c.cfg
+	if router.DB != nil {
+		registerTenantCallbacks(router.DB)
+	}
+}
+
+// RequireTenant is an HTTPInterceptor that rejects requests with 400 Bad
+// Request when tenancy is enabled and no tenant can be resolved.
+func RequireTenant(ctx HTTPContext) bool {
+	if ctx.router == nil || ctx.router.tenancy == nil {
+		return true
+	}
+	if ctx.Tenant() == "" {
+		ctx.SendStatus(http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// Tenant returns the tenant resolved for this request, or "" when tenancy is
+// disabled or no tenant could be found.
+func (ctx *HTTPContext) Tenant() string {
+	if ctx.router == nil || ctx.router.tenancy == nil {
+		return ""
+	}
+	return ctx.router.tenancy.resolve(*ctx)
+}
+
+// MigrateTenantSchema creates the Postgres schema for a tenant in
+// SchemaPerTenant mode and migrates the given models into it.
+func (cfg TenantConfig) MigrateTenantSchema(db *gorm.DB, tenant string, models ...interface{}) error {
+	schemaName, err := cfg.SchemaName(tenant)
+	if err != nil {
+		return err
+	}
+	if err := db.Exec(fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %q`, schemaName)).Error; err != nil {
+		return err
+	}
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return err
+		}
+		if err := db.Table(schemaName + "." + stmt.Schema.Table).AutoMigrate(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerTenantCallbacks installs the GORM callbacks that enforce a tenantScope
+// set on a statement. They are no-ops for statements without one, so they are
+// safe to install on a DB shared with unscoped code. Registration is idempotent.
+func registerTenantCallbacks(db *gorm.DB) {
+	cb := db.Callback()
+	if cb.Create().Get("requiem:tenant_create") != nil {
+		return
+	}
+	cb.Create().Before("gorm:create").Register("requiem:tenant_create", tenantCreateCallback)
+	cb.Query().Before("gorm:query").Register("requiem:tenant_query", tenantFilterCallback)
+	cb.Update().Before("gorm:update").Register("requiem:tenant_update", tenantUpdateCallback)
+	cb.Delete().Before("gorm:delete").Register("requiem:tenant_delete", tenantFilterCallback)
+	cb.Row().Before("gorm:row").Register("requiem:tenant_row", tenantFilterCallback)
+	cb.Raw().Before("gorm:raw").Register("requiem:tenant_raw", tenantRawCallback)
+}
+
+func currentTenantScope(db *gorm.DB) *tenantScope {
+	v, ok := db.Get(tenantSettingKey)
+	if !ok {
+		return nil
+	}
+	ts, _ := v.(*tenantScope)
+	return ts
+}
+
+// checkTenantScope reports whether a statement on a scoped handle can go ahead,
+// failing it when there is no tenant or nothing to scope it by.
+func checkTenantScope(db *gorm.DB, ts *tenantScope) bool {
+	switch {
+	case ts.id == "":
+		db.AddError(ErrNoTenant)
+	case ts.err != nil:
+		db.AddError(ts.err)
+	case db.Statement.Schema == nil, db.Statement.SQL.Len() > 0:
+		// No model to scope by, or raw SQL that clauses can't be added to.
+		db.AddError(ErrTenantUnscoped)
+	default:
+		return true
+	}
+	return false
+}
+
+// qualifyTenantTable points the statement at the tenant's schema. Returns true
+// when the statement runs in schema-per-tenant mode.
+func qualifyTenantTable(db *gorm.DB, ts *tenantScope) bool {
+	if ts.schema == "" {
+		return false
+	}
+	table := db.Statement.Table
+	if table == "" {
+		table = db.Statement.Schema.Table
+	}
+	if !strings.Contains(table, ".") {
+		db.Statement.Table = ts.schema + "." + table
+	}
+	return true
+}
+
+func tenantFilterCallback(db *gorm.DB) {
+	ts := currentTenantScope(db)
+	if ts == nil || db.Error != nil || !checkTenantScope(db, ts) {
+		return
+	}
+	if qualifyTenantTable(db, ts) {
+		return
+	}
+	if db.Statement.Schema.LookUpField(ts.column) == nil {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: ts.column}, Value: ts.id},
+	}})
+}
+
+// tenantUpdateCallback scopes updates like queries and keeps them from moving
+// rows to another tenant: a tenant column being set must hold the handle's
+// tenant, and a zero one in a saved struct is stamped with it.
+func tenantUpdateCallback(db *gorm.DB) {
+	tenantFilterCallback(db)
+	ts := currentTenantScope(db)
+	if ts == nil || db.Error != nil || ts.schema != "" {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(ts.column)
+	if field == nil {
+		return
+	}
+
+	switch dest := db.Statement.Dest.(type) {
+	case map[string]interface{}:
+		for k, v := range dest {
+			if db.Statement.Schema.LookUpField(k) == field && fmt.Sprint(v) != ts.id {
+				db.AddError(ErrTenantMismatch)
+			}
+		}
+	default:
+		rv := reflect.Indirect(reflect.ValueOf(dest))
+		if rv.Kind() != reflect.Struct || rv.Type() != db.Statement.Schema.ModelType {
+			return
+		}
+		v, zero := field.ValueOf(db.Statement.Context, rv)
+		if zero {
+			db.AddError(field.Set(db.Statement.Context, rv, ts.id))
+		} else if fmt.Sprint(v) != ts.id {
+			db.AddError(ErrTenantMismatch)
+		}
+	}
+}
+
+// tenantRawCallback fails Exec on a scoped handle: raw SQL can't be scoped.
+func tenantRawCallback(db *gorm.DB) {
+	if ts := currentTenantScope(db); ts != nil && db.Error == nil {
+		db.AddError(ErrTenantUnscoped)
+	}
+}
+
+func tenantCreateCallback(db *gorm.DB) {
+	ts := currentTenantScope(db)
+	if ts == nil || db.Error != nil || !checkTenantScope(db, ts) {
+		return
+	}
+	if qualifyTenantTable(db, ts) {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(ts.column)
+	if field == nil {
+		return
+	}
+
+	stamp := func(rv reflect.Value) {
+		v, zero := field.ValueOf(db.Statement.Context, rv)
+		if zero {
+			db.AddError(field.Set(db.Statement.Context, rv, ts.id))
+			return
+		}
+		if fmt.Sprint(v) != ts.id {
+			db.AddError(ErrTenantMismatch)
+		}
+	}
+
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			elem := reflect.Indirect(rv.Index(i))
+			if elem.Kind() == reflect.Struct {
+				stamp(elem)
+			}
+		}
+	case reflect.Struct:
+		stamp(rv)
+	}
+}
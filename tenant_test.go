@@ -0,0 +1,155 @@
+package requiem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type tenantNote struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	TenantID string `json:"tenant_id"`
+	Text     string `json:"text"`
+}
+
+type tenantNoteController struct{}
+
+func (c tenantNoteController) Load(router *Router) {
+	r := router.NewRestRouter("/notes")
+	r.Get("/", func(ctx HTTPContext) {
+		var notes []tenantNote
+		if err := ctx.DB().Find(&notes).Error; err != nil {
+			ctx.SendStatus(http.StatusInternalServerError)
+			return
+		}
+		ctx.SendJSON(notes)
+	})
+	r.Post("/", func(ctx HTTPContext) {
+		n := ctx.Body.(*tenantNote)
+		if err := ctx.DB().Create(n).Error; err != nil {
+			ctx.SendStatus(http.StatusForbidden)
+			return
+		}
+		ctx.SendJSONWithStatus(n, http.StatusCreated)
+	}, tenantNote{})
+	r.Get("/required", func(ctx HTTPContext) {
+		ctx.SendStatus(http.StatusOK)
+	}, RequireTenant)
+}
+
+func tenantRouter(t *testing.T, cfg TenantConfig) *Router {
+	t.Helper()
+	db := testDB(t, "", &tenantNote{})
+	db.Create(&[]tenantNote{
+		{TenantID: "acme", Text: "acme note"},
+		{TenantID: "globex", Text: "globex note"},
+	})
+	return newRouter(defaultBasePath, db, []IHttpController{tenantNoteController{}, &tenantController{cfg: cfg}})
+}
+
+func TestTenancy_FiltersQueriesByHeader(t *testing.T) {
+	r := tenantRouter(t, TenantConfig{Header: "X-Tenant-ID"})
+
+	rec := serve(r, http.MethodGet, "/api/notes/", "", map[string]string{"X-Tenant-ID": "acme"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var notes []tenantNote
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notes))
+	assert.Len(t, notes, 1)
+	assert.Equal(t, "acme note", notes[0].Text)
+}
+
+func TestTenancy_FailsClosedWithoutTenant(t *testing.T) {
+	r := tenantRouter(t, TenantConfig{Header: "X-Tenant-ID"})
+
+	rec := serve(r, http.MethodGet, "/api/notes/", "", nil)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	rec = serve(r, http.MethodGet, "/api/notes/required", "", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestTenancy_StampsAndEnforcesInserts(t *testing.T) {
+	r := tenantRouter(t, TenantConfig{Subdomain: true})
+	const host = "globex.example.com"
+
+	req := httptest.NewRequest(http.MethodPost, "/api/notes/", strings.NewReader(`{"text":"new"}`))
+	req.Host = host
+	rec := serveRequest(r, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	var created tenantNote
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "globex", created.TenantID)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/notes/", strings.NewReader(`{"tenant_id":"acme","text":"sneaky"}`))
+	req.Host = host
+	rec = serveRequest(r, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestTenancy_ResolvesAttributeBeforeHeader(t *testing.T) {
+	cfg := TenantConfig{Attribute: "tenant", Header: "X-Tenant-ID"}
+	ctx := newHTTPContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil, nil, nil)
+	ctx.Request.Header.Set("X-Tenant-ID", "from-header")
+	assert.Equal(t, "from-header", cfg.resolve(ctx))
+
+	ctx.SetAttribute("tenant", "from-attr")
+	assert.Equal(t, "from-attr", cfg.resolve(ctx))
+}
+
+func TestTenancy_FailsClosedWithoutModel(t *testing.T) {
+	r := tenantRouter(t, TenantConfig{Header: "X-Tenant-ID"})
+	scoped := r.DB.Scopes(r.tenancy.Scope("acme"))
+
+	var rows []map[string]interface{}
+	assert.ErrorIs(t, scoped.Table("tenant_notes").Find(&rows).Error, ErrTenantUnscoped)
+	assert.ErrorIs(t, scoped.Table("tenant_notes").Where("1=1").Update("text", "x").Error, ErrTenantUnscoped)
+	assert.ErrorIs(t, scoped.Exec("UPDATE tenant_notes SET text = 'x'").Error, ErrTenantUnscoped)
+	var raw []tenantNote
+	assert.ErrorIs(t, scoped.Raw("SELECT * FROM tenant_notes").Scan(&raw).Error, ErrTenantUnscoped)
+
+	var notes []tenantNote
+	assert.NoError(t, r.DB.Find(&notes).Error)
+	for _, n := range notes {
+		assert.NotEqual(t, "x", n.Text)
+	}
+}
+
+func TestTenancy_UpdatesKeepTenant(t *testing.T) {
+	r := tenantRouter(t, TenantConfig{Header: "X-Tenant-ID"})
+	scoped := func() *gorm.DB { return r.DB.Scopes(r.tenancy.Scope("acme")) }
+
+	var note tenantNote
+	assert.NoError(t, scoped().First(&note).Error)
+	assert.ErrorIs(t, scoped().Model(&note).Update("tenant_id", "globex").Error, ErrTenantMismatch)
+	assert.ErrorIs(t, scoped().Model(&note).Updates(map[string]interface{}{"TenantID": "globex"}).Error, ErrTenantMismatch)
+	assert.ErrorIs(t, scoped().Model(&note).Updates(tenantNote{TenantID: "globex"}).Error, ErrTenantMismatch)
+
+	// A zero tenant in a saved struct is stamped rather than cleared.
+	assert.NoError(t, scoped().Save(&tenantNote{ID: note.ID, Text: "edited"}).Error)
+	var saved tenantNote
+	assert.NoError(t, r.DB.First(&saved, note.ID).Error)
+	assert.Equal(t, "acme", saved.TenantID)
+	assert.Equal(t, "edited", saved.Text)
+}
+
+func TestTenantConfig_SchemaName(t *testing.T) {
+	name, err := TenantConfig{SchemaPrefix: "t_"}.SchemaName("acme_2")
+	assert.NoError(t, err)
+	assert.Equal(t, "t_acme_2", name)
+
+	for _, id := range []string{"Acme", "acme-corp", "acme.corp", "_acme", strings.Repeat("a", 60)} {
+		_, err := TenantConfig{}.SchemaName(id)
+		assert.ErrorIs(t, err, ErrInvalidTenant, id)
+	}
+
+	r := tenantRouter(t, TenantConfig{SchemaPerTenant: true})
+	var notes []tenantNote
+	assert.ErrorIs(t, r.DB.Scopes(r.tenancy.Scope("acme-corp")).Find(&notes).Error, ErrInvalidTenant)
+}
@@ -0,0 +1,233 @@
+package requiem
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// unionSpec is a registered interface type and its concrete implementations,
+// told apart by the value of a discriminator property.
+type unionSpec struct {
+	discriminator string
+	variants      map[string]reflect.Type
+	names         []string
+}
+
+var (
+	unionsMu sync.RWMutex
+	unions   = map[reflect.Type]*unionSpec{}
+)
+
+// RegisterUnion registers the concrete implementations of the interface type T
+// so interface-typed fields are documented as a oneOf with a discriminator and
+// decoded into the right type. variants maps each discriminator value to a
+// zero value of its implementation (a struct or a pointer to one):
+//
+//	requiem.RegisterUnion[Event]("type", map[string]any{
+//		"click": ClickEvent{},
+//		"view":  &ViewEvent{},
+//	})
+//
+// Each variant must itself marshal the discriminator property (e.g. with a
+// `json:"type"` field) so clients can tell responses apart. Call it during
+// initialization, before the server starts.
+func RegisterUnion[T any](discriminator string, variants map[string]any) {
+	iface := reflect.TypeOf((*T)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("requiem: RegisterUnion: %s is not an interface type", iface))
+	}
+	spec := &unionSpec{discriminator: discriminator, variants: map[string]reflect.Type{}}
+	for name, v := range variants {
+		t := reflect.TypeOf(v)
+		if t == nil || !t.Implements(iface) {
+			panic(fmt.Sprintf("requiem: RegisterUnion: %v for %q does not implement %s", t, name, iface))
+		}
+		spec.variants[name] = t
+		spec.names = append(spec.names, name)
+	}
+	sort.Strings(spec.names)
+
+	unionsMu.Lock()
+	unions[iface] = spec
+	unionsMu.Unlock()
+}
+
+func unionFor(t reflect.Type) *unionSpec {
+	unionsMu.RLock()
+	defer unionsMu.RUnlock()
+	return unions[t]
+}
+
+// unionSchema documents a registered interface as a oneOf of its variants with
+// a discriminator. Named interfaces get their own component.
+func (db *docBuilder) unionSchema(t reflect.Type, u *unionSpec) map[string]interface{} {
+	build := func() map[string]interface{} {
+		oneOf := make([]interface{}, 0, len(u.names))
+		mapping := map[string]interface{}{}
+		for _, name := range u.names {
+			schema := db.schemaFor(u.variants[name])
+			if ref, ok := schema["$ref"].(string); ok {
+				mapping[name] = ref
+			}
+			oneOf = append(oneOf, schema)
+		}
+		discriminator := map[string]interface{}{"propertyName": u.discriminator}
+		if len(mapping) > 0 {
+			discriminator["mapping"] = mapping
+		}
+		return map[string]interface{}{"oneOf": oneOf, "discriminator": discriminator}
+	}
+
+	name := t.Name()
+	if name == "" {
+		return build()
+	}
+	if _, ok := db.schemas[name]; !ok {
+		db.schemas[name] = map[string]interface{}{}
+		db.schemas[name] = build()
+	}
+	return map[string]interface{}{"$ref": openapiRefPrefix + name}
+}
+
+// containsUnion reports whether values of t can hold a registered union, so
+// decoding only pays for the second pass when it's needed.
+func containsUnion(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		return unionFor(t) != nil
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return containsUnion(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() && containsUnion(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeUnions fills the registered-union values in v from raw, the JSON that
+// was already decoded into v. encoding/json can't decode into non-empty
+// interfaces, so those values are left nil by the first pass.
+func decodeUnions(raw json.RawMessage, v reflect.Value) error {
+	if len(raw) == 0 || string(raw) == "null" || !containsUnion(v.Type(), map[reflect.Type]bool{}) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		u := unionFor(v.Type())
+		var probe map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &probe); err != nil {
+			return err
+		}
+		var kind string
+		if err := json.Unmarshal(probe[u.discriminator], &kind); err != nil {
+			return fmt.Errorf("missing or invalid discriminator %q", u.discriminator)
+		}
+		vt, ok := u.variants[kind]
+		if !ok {
+			return fmt.Errorf("unknown %s %q", u.discriminator, kind)
+		}
+		ptr := reflect.New(vt)
+		if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+			return err
+		}
+		if err := decodeUnions(raw, ptr.Elem()); err != nil {
+			return err
+		}
+		v.Set(ptr.Elem())
+
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return decodeUnions(raw, v.Elem())
+		}
+
+	case reflect.Struct:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return err
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if !f.IsExported() || tag == "-" {
+				continue
+			}
+			if f.Anonymous && tag == "" {
+				if err := decodeUnions(raw, v.Field(i)); err != nil {
+					return err
+				}
+				continue
+			}
+			name := f.Name
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+			fieldRaw, ok := fields[name]
+			if !ok {
+				// encoding/json matches keys case-insensitively too.
+				for k, r := range fields {
+					if strings.EqualFold(k, name) {
+						fieldRaw, ok = r, true
+						break
+					}
+				}
+			}
+			if ok {
+				if err := decodeUnions(fieldRaw, v.Field(i)); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return err
+		}
+		if v.Kind() == reflect.Slice && v.Len() != len(items) {
+			v.Set(reflect.MakeSlice(v.Type(), len(items), len(items)))
+		}
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			if err := decodeUnions(items[i], v.Index(i)); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+
+	case reflect.Map:
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for k, r := range entries {
+			key := reflect.New(v.Type().Key()).Elem()
+			if key.Kind() != reflect.String {
+				continue
+			}
+			key.SetString(k)
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if existing := v.MapIndex(key); existing.IsValid() {
+				elem.Set(existing)
+			}
+			if err := decodeUnions(r, elem); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			v.SetMapIndex(key, elem)
+		}
+	}
+	return nil
+}
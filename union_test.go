@@ -0,0 +1,110 @@
+package requiem
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Event interface {
+	eventType() string
+}
+
+type ClickEvent struct {
+	Type string `json:"type"`
+	X    int    `json:"x"`
+}
+
+func (ClickEvent) eventType() string { return "click" }
+
+type ViewEvent struct {
+	Type string `json:"type"`
+	Page string `json:"page"`
+}
+
+func (*ViewEvent) eventType() string { return "view" }
+
+type EventBatch struct {
+	Source string  `json:"source"`
+	First  Event   `json:"first"`
+	Events []Event `json:"events"`
+}
+
+func registerEvents() {
+	RegisterUnion[Event]("type", map[string]any{
+		"click": ClickEvent{},
+		"view":  &ViewEvent{},
+	})
+}
+
+type eventController struct {
+	got chan *EventBatch
+}
+
+func (c eventController) Load(router *Router) {
+	router.NewRestRouter("/events").Post("/", func(ctx HTTPContext) {
+		c.got <- ctx.Body.(*EventBatch)
+		ctx.SendStatus(http.StatusAccepted)
+	}, EventBatch{})
+}
+
+func TestUnion_SchemaHasOneOfAndDiscriminator(t *testing.T) {
+	registerEvents()
+	db := newDocBuilder()
+	db.schemaFor(reflect.TypeOf(EventBatch{}))
+
+	assert.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/Event"},
+		db.schemas["EventBatch"]["properties"].(map[string]interface{})["first"])
+
+	event := db.schemas["Event"]
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"$ref": "#/components/schemas/ClickEvent"},
+		map[string]interface{}{"$ref": "#/components/schemas/ViewEvent"},
+	}, event["oneOf"])
+	assert.Equal(t, map[string]interface{}{
+		"propertyName": "type",
+		"mapping": map[string]interface{}{
+			"click": "#/components/schemas/ClickEvent",
+			"view":  "#/components/schemas/ViewEvent",
+		},
+	}, event["discriminator"])
+	assert.Contains(t, db.schemas, "ViewEvent")
+}
+
+func TestUnion_MCPDefsRewriteMapping(t *testing.T) {
+	registerEvents()
+	router := newRouter("/api", nil, []IHttpController{eventController{}})
+	schema := buildInputSchema(router.routes[0])
+
+	event := schema["$defs"].(map[string]interface{})["Event"].(map[string]interface{})
+	mapping := event["discriminator"].(map[string]interface{})["mapping"].(map[string]interface{})
+	assert.Equal(t, "#/$defs/ClickEvent", mapping["click"])
+	assert.Equal(t, "#/$defs/ViewEvent", event["oneOf"].([]interface{})[1].(map[string]interface{})["$ref"])
+}
+
+func TestUnion_BodyDecodesConcreteTypes(t *testing.T) {
+	registerEvents()
+	c := eventController{got: make(chan *EventBatch, 1)}
+	router := newRouter("/api", nil, []IHttpController{c})
+
+	rec := serve(router, http.MethodPost, "/api/events/",
+		`{"source": "web", "first": {"type": "view", "page": "/home"},
+		  "events": [{"type": "click", "x": 4}, {"type": "view", "page": "/about"}]}`, nil)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	batch := <-c.got
+	assert.Equal(t, "web", batch.Source)
+	assert.Equal(t, &ViewEvent{Type: "view", Page: "/home"}, batch.First)
+	assert.Equal(t, []Event{ClickEvent{Type: "click", X: 4}, &ViewEvent{Type: "view", Page: "/about"}}, batch.Events)
+
+	// Round-trips through encoding/json with the concrete types.
+	out, _ := json.Marshal(batch)
+	assert.Contains(t, string(out), `{"type":"click","x":4}`)
+
+	rec = serve(router, http.MethodPost, "/api/events/",
+		`{"first": {"type": "scroll"}}`, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,188 @@
+package requiem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// RequestValidationError is the 400 body sent when OpenAPIConfig.
+// ValidateRequests is on and a request doesn't match its operation.
+type RequestValidationError struct {
+	Message string            `json:"message"`
+	Errors  []ValidationIssue `json:"errors"`
+}
+
+// ValidationIssue is one way a request broke its operation's contract.
+type ValidationIssue struct {
+	// In is where the offending value was: path, query, header, cookie or body.
+	In     string `json:"in"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Enum restricts a declared parameter to the given values. They appear as the
+// parameter's enum in the spec and MCP schema, and are enforced when
+// OpenAPIConfig.ValidateRequests is on. A path variable not yet declared with
+// Param is declared implicitly; any other undeclared name is fatal.
+func (rt *Route) Enum(param string, values ...string) *Route {
+	found := false
+	for i := range rt.params {
+		if rt.params[i].name == param {
+			rt.params[i].enum = append(rt.params[i].enum, values...)
+			found = true
+		}
+	}
+	if found {
+		return rt
+	}
+	spec, ok := pathParamSpecs(rt.path)[param]
+	if !ok {
+		Logger.Fatal("[%s] %s => Enum on undeclared parameter %q", rt.method, rt.path, param)
+		return rt
+	}
+	spec.enum = values
+	rt.params = append(rt.params, spec)
+	return rt
+}
+
+// enumValues converts a parameter's enum to its declared type, so an integer
+// enum renders as [1, 2] rather than ["1", "2"].
+func enumValues(p paramSpec) []interface{} {
+	out := make([]interface{}, 0, len(p.enum))
+	for _, v := range p.enum {
+		switch p.typ {
+		case "integer":
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				out = append(out, n)
+				continue
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				out = append(out, f)
+				continue
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(v); err == nil {
+				out = append(out, b)
+				continue
+			}
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// validateParams checks a request's parameters against the route's declared
+// ones: the same declarations its OpenAPI operation is built from.
+func validateParams(rt *Route, r *http.Request) []ValidationIssue {
+	var issues []ValidationIssue
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	for _, p := range rt.params {
+		var values []string
+		switch p.in {
+		case "path":
+			if v, ok := vars[p.name]; ok {
+				values = []string{v}
+			}
+		case "query":
+			values = query[p.name]
+		case "header":
+			values = r.Header.Values(p.name)
+		case "cookie":
+			if c, err := r.Cookie(p.name); err == nil {
+				values = []string{c.Value}
+			}
+		}
+
+		if len(values) == 0 {
+			if p.required || p.in == "path" {
+				issues = append(issues, ValidationIssue{In: p.in, Name: p.name, Reason: "is required"})
+			}
+			continue
+		}
+		for _, v := range values {
+			if reason := checkParamValue(p, v); reason != "" {
+				issues = append(issues, ValidationIssue{In: p.in, Name: p.name, Reason: reason})
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// typeMismatch is the reason reported for a param value that doesn't parse as typ.
+func typeMismatch(typ, v string) string {
+	return fmt.Sprintf("must be of type %s, got %q", typ, v)
+}
+
+func checkParamValue(p paramSpec, v string) string {
+	var err error
+	switch p.typ {
+	case "integer":
+		_, err = strconv.ParseInt(v, 10, 64)
+	case "number":
+		_, err = strconv.ParseFloat(v, 64)
+	case "boolean":
+		_, err = strconv.ParseBool(v)
+	}
+	if err != nil {
+		return typeMismatch(p.typ, v)
+	}
+	if len(p.enum) > 0 {
+		for _, e := range p.enum {
+			if e == v {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of [%s], got %q", strings.Join(p.enum, ", "), v)
+	}
+	return ""
+}
+
+// bodyIssues describes validator failures on a request body.
+func bodyIssues(err error) []ValidationIssue {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		typ := typeErr.Type.String()
+		if t, ok := newDocBuilder().schemaFor(typeErr.Type)["type"].(string); ok {
+			typ = t
+		}
+		return []ValidationIssue{{In: "body", Name: typeErr.Field, Reason: fmt.Sprintf("must be of type %s, got %s", typ, typeErr.Value)}}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return []ValidationIssue{{In: "body", Reason: "is not valid JSON: " + err.Error()}}
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return []ValidationIssue{{In: "body", Reason: err.Error()}}
+	}
+	issues := make([]ValidationIssue, 0, len(verrs))
+	for _, fe := range verrs {
+		// Namespace is "Type.Field.Sub"; drop the type name.
+		name := fe.Namespace()
+		if i := strings.Index(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		reason := "failed " + fe.Tag()
+		if fe.Param() != "" {
+			reason += "=" + fe.Param()
+		}
+		issues = append(issues, ValidationIssue{In: "body", Name: name, Reason: reason})
+	}
+	return issues
+}
+
+func sendValidationError(ctx HTTPContext, issues []ValidationIssue) {
+	ctx.SendJSONWithStatus(RequestValidationError{Message: "Request does not match the API contract", Errors: issues}, http.StatusBadRequest)
+}
@@ -0,0 +1,152 @@
+package requiem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type contractController struct{}
+
+func (contractController) Load(router *Router) {
+	r := router.NewRestRouter("/contract")
+	r.Get("/{id}", func(ctx HTTPContext) {
+		ctx.SendStatus(http.StatusOK)
+	}).
+		Param("id", "integer", "Item id").
+		Query("limit", "integer", false, "Max items").
+		Query("sort", "string", false, "Sort order").
+		Enum("sort", "asc", "desc").
+		Header("X-Tenant", "string", true, "Tenant")
+
+	r.Post("/", func(ctx HTTPContext) {
+		ctx.SendStatus(http.StatusCreated)
+	}, CreateWidget{}).
+		Query("dry_run", "boolean", false, "Validate only")
+
+	deny := func(ctx HTTPContext) bool {
+		ctx.SendStatus(http.StatusUnauthorized)
+		return false
+	}
+	r.Get("/kind/{kind}", func(ctx HTTPContext) {
+		ctx.SendStatus(http.StatusOK)
+	}).Enum("kind", "big", "small")
+	r.Post("/secure", func(ctx HTTPContext) {
+		ctx.SendStatus(http.StatusCreated)
+	}, CreateWidget{}, deny).
+		Query("limit", "integer", true, "Max items")
+}
+
+func contractRouter(validate bool) *Router {
+	return newRouter("/api", nil, []IHttpController{
+		contractController{},
+		&openapiController{cfg: OpenAPIConfig{Title: "T", Version: "1", ValidateRequests: validate}},
+	})
+}
+
+// serveContract serves req and decodes the validation error body, if any.
+func serveContract(r *Router, req *http.Request) (*httptest.ResponseRecorder, RequestValidationError) {
+	rec := serveRequest(r, req)
+	var body RequestValidationError
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	return rec, body
+}
+
+func TestValidateRequests_Params(t *testing.T) {
+	r := contractRouter(true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/contract/abc?limit=x&sort=up", nil)
+	rec, body := serveContract(r, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, []ValidationIssue{
+		{In: "path", Name: "id", Reason: `must be of type integer, got "abc"`},
+		{In: "query", Name: "limit", Reason: `must be of type integer, got "x"`},
+		{In: "query", Name: "sort", Reason: `must be one of [asc, desc], got "up"`},
+		{In: "header", Name: "X-Tenant", Reason: "is required"},
+	}, body.Errors)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/contract/7?limit=10&sort=asc", nil)
+	req.Header.Set("X-Tenant", "acme")
+	rec, _ = serveContract(r, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestValidateRequests_BodyDetails(t *testing.T) {
+	r := contractRouter(true)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/contract/?dry_run=maybe", strings.NewReader(`{"quantity": 2}`))
+	rec, body := serveContract(r, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, []ValidationIssue{
+		{In: "query", Name: "dry_run", Reason: `must be of type boolean, got "maybe"`},
+		{In: "body", Name: "Name", Reason: "failed required"},
+	}, body.Errors)
+}
+
+func TestValidateRequests_OffByDefault(t *testing.T) {
+	r := contractRouter(false)
+
+	rec, _ := serveContract(r, httptest.NewRequest(http.MethodGet, "/api/contract/abc?limit=x", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec, _ = serveContract(r, httptest.NewRequest(http.MethodPost, "/api/contract/", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestValidateRequests_InterceptorsRunFirst(t *testing.T) {
+	r := contractRouter(true)
+
+	rec, _ := serveContract(r, httptest.NewRequest(http.MethodPost, "/api/contract/secure", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestEnum_DeclaresPathParam(t *testing.T) {
+	r := contractRouter(true)
+
+	rec, body := serveContract(r, httptest.NewRequest(http.MethodGet, "/api/contract/kind/huge", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, []ValidationIssue{{In: "path", Name: "kind", Reason: `must be one of [big, small], got "huge"`}}, body.Errors)
+
+	rec, _ = serveContract(r, httptest.NewRequest(http.MethodGet, "/api/contract/kind/big", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestEnum_InSpec(t *testing.T) {
+	r := contractRouter(false)
+	var spec map[string]interface{}
+	json.Unmarshal(buildDoc(OpenAPIConfig{Title: "T", Version: "1"}, r.routes), &spec)
+
+	params := spec["paths"].(map[string]interface{})["/contract/{id}"].(map[string]interface{})["get"].(map[string]interface{})["parameters"].([]interface{})
+	sort := params[2].(map[string]interface{})
+	assert.Equal(t, "sort", sort["name"])
+	assert.Equal(t, []interface{}{"asc", "desc"}, sort["schema"].(map[string]interface{})["enum"])
+}
+
+func TestValidateRequests_MalformedBody(t *testing.T) {
+	r := contractRouter(true)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/contract/", strings.NewReader(`{"name": "x", "quantity": "two"}`))
+	rec, body := serveContract(r, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, []ValidationIssue{
+		{In: "body", Name: "quantity", Reason: "must be of type integer, got string"},
+	}, body.Errors)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/contract/", strings.NewReader(`{"name": "x",`))
+	rec, body = serveContract(r, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, []ValidationIssue{
+		{In: "body", Reason: "is not valid JSON: unexpected EOF"},
+	}, body.Errors)
+
+	// Without validation, ReadJSON's tolerance is kept.
+	req = httptest.NewRequest(http.MethodPost, "/api/contract/", strings.NewReader(`{"name": "x", "quantity": "two"}`))
+	rec, _ = serveContract(contractRouter(false), req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
@@ -0,0 +1,642 @@
+package requiem
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultWebhookDeadLetterPath = "/webhooks/dead-letters"
+	defaultWebhookMaxAttempts    = 8
+	defaultWebhookBaseDelay      = 10 * time.Second
+	defaultWebhookMaxDelay       = time.Hour
+	defaultWebhookPollInterval   = 5 * time.Second
+	defaultWebhookTimeout        = 10 * time.Second
+	defaultWebhookBatchSize      = 100
+	defaultWebhookConcurrency    = 4
+	defaultDeadLetterQueryLimit  = 100
+	maxDeadLetterQueryLimit      = 1000
+	// webhookErrorSnippet bounds how much of a failed response body is kept
+	// in WebhookDelivery.LastError.
+	webhookErrorSnippet = 512
+)
+
+// Headers sent with every webhook delivery.
+const (
+	// WebhookIDHeader carries the delivery's EventID. It is stable across
+	// retries and replays, so receivers can use it to drop duplicates.
+	WebhookIDHeader = "X-Webhook-ID"
+	// WebhookEventHeader carries the webhook (or callback) name.
+	WebhookEventHeader = "X-Webhook-Event"
+	// WebhookTimestampHeader carries the Unix time the attempt was signed.
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	// WebhookSignatureHeader carries SignWebhook's result when a secret is
+	// configured.
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// ErrWebhookSignature is returned by VerifyWebhookSignature for deliveries
+// that are unsigned, signed with another secret, tampered with or too old.
+var ErrWebhookSignature = errors.New("requiem: invalid webhook signature")
+
+// Webhook is an event the API sends to its subscribers. Register one with
+// Server.Webhook: it is documented under the spec's webhooks and delivered
+// through the outbox by Enqueue.
+type Webhook struct {
+	name        string
+	payloadType reflect.Type
+	summary     string
+	description string
+	tags        []string
+}
+
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+func (w *Webhook) Summary(s string) *Webhook {
+	w.summary = s
+	return w
+}
+
+func (w *Webhook) Description(s string) *Webhook {
+	w.description = s
+	return w
+}
+
+func (w *Webhook) Tags(t ...string) *Webhook {
+	w.tags = append(w.tags, t...)
+	return w
+}
+
+// Enqueue queues payload for delivery to url. Pass the transaction that makes
+// the change the event announces, so the delivery is only sent if it commits.
+// The payload must have the type the webhook was registered with, or be a
+// pointer to it.
+func (w *Webhook) Enqueue(db *gorm.DB, url string, payload interface{}) (*WebhookDelivery, error) {
+	if w.payloadType != nil {
+		t := reflect.TypeOf(payload)
+		if t != w.payloadType && (t == nil || t.Kind() != reflect.Ptr || t.Elem() != w.payloadType) {
+			return nil, fmt.Errorf("webhook %q expects a %s payload, got %T", w.name, w.payloadType, payload)
+		}
+	}
+	return EnqueueCallback(db, w.name, url, payload)
+}
+
+// EnqueueCallback queues payload for delivery to url under the given event
+// name, for callbacks documented with Route.Callback whose URL comes from the
+// request. Webhook.Enqueue is the type-checked equivalent for registered
+// webhooks.
+func EnqueueCallback(db *gorm.DB, name, url string, payload interface{}) (*WebhookDelivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	del := &WebhookDelivery{
+		EventID:       hex.EncodeToString(id),
+		Webhook:       name,
+		URL:           url,
+		Payload:       string(body),
+		NextAttemptAt: time.Now().UTC(),
+	}
+	if err := db.Create(del).Error; err != nil {
+		return nil, err
+	}
+	return del, nil
+}
+
+// WebhookDelivery is a pending delivery in the webhook_deliveries outbox.
+type WebhookDelivery struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	EventID       string    `json:"event_id" gorm:"index"`
+	Webhook       string    `json:"webhook" gorm:"index"`
+	URL           string    `json:"url"`
+	Payload       string    `json:"payload"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"index"`
+	LastStatus    int       `json:"last_status,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// WebhookDeadLetter is a delivery that failed WebhookConfig.MaxAttempts times,
+// kept in webhook_dead_letters until it is replayed.
+type WebhookDeadLetter struct {
+	WebhookDelivery
+	FailedAt time.Time `json:"failed_at" gorm:"index"`
+}
+
+// WebhookConfig configures webhook delivery, enabled by Server.UseWebhooks.
+type WebhookConfig struct {
+	// Secret signs every delivery; see SignWebhook. Deliveries are unsigned
+	// when it is empty.
+	Secret string
+	// SecretFor returns the secret for a target URL, for subscribers with
+	// secrets of their own. It overrides Secret.
+	SecretFor func(url string) string
+	// MaxAttempts is how many times a delivery is tried before it moves to
+	// the dead-letter table. Defaults to 8.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles on every
+	// further one up to MaxDelay. Default to 10 seconds and one hour.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// PollInterval is how often the outbox is checked for due deliveries.
+	// Defaults to 5 seconds.
+	PollInterval time.Duration
+	// Timeout bounds each attempt. Defaults to 10 seconds.
+	Timeout time.Duration
+	// BatchSize caps the deliveries taken per poll, and Concurrency how many
+	// of them are sent at once. Default to 100 and 4.
+	BatchSize   int
+	Concurrency int
+	// Client sends the deliveries. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Path overrides the dead-letter admin endpoints. Defaults to the API's
+	// common route prefix plus "/webhooks/dead-letters". GET lists dead
+	// letters and POST <path>/{id}/replay queues one for delivery again. They
+	// are only mounted when Admin is set.
+	Path string
+	// Admin gates the dead-letter endpoints; it should reject non-admin callers.
+	Admin HTTPInterceptor
+}
+
+func (cfg WebhookConfig) withDefaults() WebhookConfig {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = defaultWebhookBaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = defaultWebhookMaxDelay
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultWebhookPollInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultWebhookBatchSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultWebhookConcurrency
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return cfg
+}
+
+// Webhook registers an event the API sends to subscribers and documents it
+// in the spec, under "webhooks" for OpenAPI 3.1 and "x-webhooks" for 3.0.
+// payloadType is an instance of the body's type. Deliveries are only sent
+// once UseWebhooks is called.
+func (s *Server) Webhook(name string, payloadType interface{}) *Webhook {
+	c := s.webhookController()
+	for _, w := range c.hooks {
+		if w.name == name {
+			Logger.Fatal("Duplicate webhook %q", name)
+		}
+	}
+	w := &Webhook{name: name}
+	if payloadType != nil {
+		w.payloadType = reflect.TypeOf(payloadType)
+	}
+	c.hooks = append(c.hooks, w)
+	return w
+}
+
+// UseWebhooks enables webhook delivery. Enqueued deliveries are kept in an
+// outbox table in the server's database and sent by Start in the background,
+// retried with exponential backoff, and moved to a dead-letter table once
+// they run out of attempts. Delivery is at-least-once: receivers should drop
+// duplicate WebhookIDHeader values.
+func (s *Server) UseWebhooks(cfg WebhookConfig) {
+	if c := s.webhookController(); c.cfg == nil {
+		c.cfg = &cfg
+	}
+}
+
+func (s *Server) webhookController() *webhookController {
+	if s.webhooks == nil {
+		s.webhooks = &webhookController{}
+		s.controllers = append(s.controllers, s.webhooks)
+	}
+	return s.webhooks
+}
+
+type webhookController struct {
+	hooks []*Webhook
+	cfg   *WebhookConfig
+}
+
+func (c *webhookController) Load(router *Router) {
+	router.webhooks = c.hooks
+	if c.cfg == nil {
+		return
+	}
+	if router.DB == nil {
+		if Logger != nil {
+			Logger.Warn("Webhook delivery needs a database; enqueued webhooks will not be sent")
+		}
+		return
+	}
+	if err := router.DB.AutoMigrate(&WebhookDelivery{}, &WebhookDeadLetter{}); err != nil && Logger != nil {
+		Logger.Error("Could not migrate webhook tables: %s", err)
+	}
+	router.webhookDispatcher = newWebhookDispatcher(router.DB, *c.cfg)
+
+	if c.cfg.Admin == nil {
+		return
+	}
+	// The default path sits under the routes' common prefix, which is only
+	// known once every controller has registered its routes.
+	router.loaded = append(router.loaded, func() {
+		path := c.cfg.Path
+		if path == "" {
+			router.mu.RLock()
+			path = commonRoutePrefix(router.routes) + defaultWebhookDeadLetterPath
+			router.mu.RUnlock()
+		}
+		router.mount(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request) {
+			ctx := newHTTPContext(w, r, nil, router, nil)
+			if c.cfg.Admin(ctx) {
+				serveDeadLetters(ctx, router.DB)
+			}
+		})
+		router.mount(http.MethodPost, path+"/{id:[0-9]+}/replay", func(w http.ResponseWriter, r *http.Request) {
+			ctx := newHTTPContext(w, r, nil, router, nil)
+			if c.cfg.Admin(ctx) {
+				serveReplay(ctx, router.DB)
+			}
+		})
+	})
+}
+
+func serveDeadLetters(ctx HTTPContext, db *gorm.DB) {
+	limit, offset := defaultDeadLetterQueryLimit, 0
+	var err error
+	if s := ctx.GetQueryParam("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 0 {
+			ctx.SendStatus(http.StatusBadRequest)
+			return
+		}
+	}
+	if limit > maxDeadLetterQueryLimit {
+		limit = maxDeadLetterQueryLimit
+	}
+	if s := ctx.GetQueryParam("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			ctx.SendStatus(http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx := db.Model(&WebhookDeadLetter{})
+	if name := ctx.GetQueryParam("webhook"); name != "" {
+		tx = tx.Where("webhook = ?", name)
+	}
+	letters := []WebhookDeadLetter{}
+	if err := tx.Order("failed_at DESC, id DESC").Limit(limit).Offset(offset).Find(&letters).Error; err != nil {
+		ctx.SendStatus(http.StatusInternalServerError)
+		return
+	}
+	ctx.SendJSON(letters)
+}
+
+func serveReplay(ctx HTTPContext, db *gorm.DB) {
+	id, _ := strconv.ParseUint(ctx.GetParam("id"), 10, 64)
+	del, err := ReplayDeadLetter(db, uint(id))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.SendStatus(http.StatusNotFound)
+	case err != nil:
+		ctx.SendStatus(http.StatusInternalServerError)
+	default:
+		ctx.SendJSONWithStatus(del, http.StatusAccepted)
+	}
+}
+
+// ReplayDeadLetter moves a dead letter back into the outbox with its attempts
+// reset, to be delivered on the next poll. It keeps its EventID. Returns
+// gorm.ErrRecordNotFound if there is no dead letter with the given ID.
+func ReplayDeadLetter(db *gorm.DB, id uint) (*WebhookDelivery, error) {
+	var del WebhookDelivery
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var dl WebhookDeadLetter
+		if err := tx.First(&dl, id).Error; err != nil {
+			return err
+		}
+		del = dl.WebhookDelivery
+		del.ID = 0
+		del.Attempts = 0
+		del.NextAttemptAt = time.Now().UTC()
+		if err := tx.Create(&del).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&dl).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &del, nil
+}
+
+// DeliverWebhooks makes one pass over the outbox in db, sending every
+// delivery that is due, and returns how many succeeded. Server.Start runs it
+// every WebhookConfig.PollInterval; tests can call it directly to deliver
+// synchronously.
+func DeliverWebhooks(db *gorm.DB, cfg WebhookConfig) (int, error) {
+	return newWebhookDispatcher(db, cfg).deliverDue(context.Background())
+}
+
+type webhookDispatcher struct {
+	db  *gorm.DB
+	cfg WebhookConfig
+	now func() time.Time
+}
+
+func newWebhookDispatcher(db *gorm.DB, cfg WebhookConfig) *webhookDispatcher {
+	return &webhookDispatcher{
+		db:  db,
+		cfg: cfg.withDefaults(),
+		now: func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// startWebhooks polls the outbox every PollInterval until the returned stop
+// function is called.
+func (r *Router) startWebhooks() func() {
+	d := r.webhookDispatcher
+	if d == nil {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		t := time.NewTicker(d.cfg.PollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if _, err := d.deliverDue(ctx); err != nil && ctx.Err() == nil {
+					Logger.Error("Could not deliver webhooks: %s", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return cancel
+}
+
+func (d *webhookDispatcher) deliverDue(ctx context.Context) (int, error) {
+	var due []WebhookDelivery
+	err := d.db.WithContext(ctx).Where("next_attempt_at <= ?", d.now()).
+		Order("next_attempt_at, id").Limit(d.cfg.BatchSize).Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		sent     int
+		firstErr error
+	)
+	sem := make(chan struct{}, d.cfg.Concurrency)
+	for _, del := range due {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(del WebhookDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ok, err := d.attempt(ctx, del)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				sent++
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(del)
+	}
+	wg.Wait()
+	return sent, firstErr
+}
+
+// attempt sends one delivery and records the outcome: success removes it from
+// the outbox, failure schedules a retry or dead-letters it.
+func (d *webhookDispatcher) attempt(ctx context.Context, del WebhookDelivery) (bool, error) {
+	now := d.now()
+	// Claim the delivery by bumping its attempt count, leasing it long enough
+	// to finish. Another instance polling the same outbox that got there
+	// first leaves nothing to update; one that crashes mid-attempt lets the
+	// lease lapse so the delivery is retried.
+	claim := d.db.WithContext(ctx).Model(&WebhookDelivery{}).
+		Where("id = ? AND attempts = ?", del.ID, del.Attempts).
+		Updates(map[string]interface{}{"attempts": del.Attempts + 1, "next_attempt_at": now.Add(2 * d.cfg.Timeout)})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return false, claim.Error
+	}
+	del.Attempts++
+
+	status, err := d.send(ctx, del)
+	if err == nil {
+		return true, d.db.WithContext(ctx).Delete(&WebhookDelivery{}, del.ID).Error
+	}
+	del.LastStatus, del.LastError = status, err.Error()
+
+	if del.Attempts >= d.cfg.MaxAttempts {
+		if Logger != nil {
+			Logger.Warn("Webhook %s to %s failed %d times, moving it to dead letters: %s", del.Webhook, del.URL, del.Attempts, err)
+		}
+		return false, d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			dl := WebhookDeadLetter{WebhookDelivery: del, FailedAt: now}
+			dl.ID = 0
+			if err := tx.Create(&dl).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&WebhookDelivery{}, del.ID).Error
+		})
+	}
+	return false, d.db.WithContext(ctx).Model(&WebhookDelivery{}).Where("id = ?", del.ID).Updates(map[string]interface{}{
+		"next_attempt_at": now.Add(d.backoff(del.Attempts)),
+		"last_status":     del.LastStatus,
+		"last_error":      del.LastError,
+	}).Error
+}
+
+// backoff returns the wait after the given number of failed attempts:
+// BaseDelay, doubling each time, capped at MaxDelay.
+func (d *webhookDispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.BaseDelay
+	for i := 1; i < attempts && delay < d.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.MaxDelay {
+		delay = d.cfg.MaxDelay
+	}
+	return delay
+}
+
+// send POSTs the delivery and returns the response status, with an error for
+// transport failures and non-2xx statuses.
+func (d *webhookDispatcher) send(ctx context.Context, del WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.URL, strings.NewReader(del.Payload))
+	if err != nil {
+		return 0, err
+	}
+	ts := d.now().Unix()
+	req.Header.Set("Content-Type", MediaTypeJSON)
+	req.Header.Set(WebhookIDHeader, del.EventID)
+	req.Header.Set(WebhookEventHeader, del.Webhook)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(ts, 10))
+	secret := d.cfg.Secret
+	if d.cfg.SecretFor != nil {
+		secret = d.cfg.SecretFor(del.URL)
+	}
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, ts, []byte(del.Payload)))
+	}
+
+	res, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(res.Body, webhookErrorSnippet))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("status %d: %s", res.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return res.StatusCode, nil
+}
+
+// SignWebhook returns the WebhookSignatureHeader value for a body sent at
+// timestamp (Unix seconds): "sha256=" and the hex HMAC-SHA256, keyed with
+// secret, of the timestamp, a dot and the body.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a received delivery's signature headers
+// against body, for receivers and tests. Deliveries signed more than
+// tolerance ago are rejected to prevent replays; zero disables the check.
+func VerifyWebhookSignature(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing timestamp", ErrWebhookSignature)
+	}
+	if tolerance > 0 {
+		if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrWebhookSignature)
+		}
+	}
+	if !hmac.Equal([]byte(header.Get(WebhookSignatureHeader)), []byte(SignWebhook(secret, ts, body))) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
+
+// Callback documents a request the API makes back to the caller after this
+// operation, to a URL given by a runtime expression such as
+// "{$request.body#/callbackUrl}". Deliver it with EnqueueCallback under the
+// same name.
+func (rt *Route) Callback(name, expression string, payloadType interface{}) *Route {
+	cb := callbackSpec{name: name, expression: expression}
+	if payloadType != nil {
+		cb.typ = reflect.TypeOf(payloadType)
+	}
+	rt.callbacks = append(rt.callbacks, cb)
+	return rt
+}
+
+type callbackSpec struct {
+	name       string
+	expression string
+	typ        reflect.Type
+}
+
+// buildWebhooks documents the registered webhooks as path items keyed by name.
+func (db *docBuilder) buildWebhooks(webhooks []*Webhook) map[string]interface{} {
+	out := make(map[string]interface{}, len(webhooks))
+	for _, w := range webhooks {
+		op := db.deliveryOperation(w.payloadType)
+		if w.summary != "" {
+			op["summary"] = w.summary
+		}
+		if w.description != "" {
+			op["description"] = w.description
+		}
+		if len(w.tags) > 0 {
+			op["tags"] = w.tags
+		}
+		out[w.name] = map[string]interface{}{"post": op}
+	}
+	return out
+}
+
+// buildCallbacks documents a route's callbacks as an operation's callbacks map.
+func (db *docBuilder) buildCallbacks(callbacks []callbackSpec) map[string]interface{} {
+	out := make(map[string]interface{}, len(callbacks))
+	for _, cb := range callbacks {
+		out[cb.name] = map[string]interface{}{
+			cb.expression: map[string]interface{}{"post": db.deliveryOperation(cb.typ)},
+		}
+	}
+	return out
+}
+
+// deliveryOperation describes the POST the dispatcher makes to a receiver.
+func (db *docBuilder) deliveryOperation(t reflect.Type) map[string]interface{} {
+	op := map[string]interface{}{
+		"parameters": []map[string]interface{}{
+			paramObject(paramSpec{name: WebhookIDHeader, in: "header", required: true, description: "Delivery ID, stable across retries"}),
+			paramObject(paramSpec{name: WebhookEventHeader, in: "header", required: true, description: "Event name"}),
+			paramObject(paramSpec{name: WebhookTimestampHeader, in: "header", typ: "integer", required: true, description: "Unix time the attempt was signed"}),
+			paramObject(paramSpec{name: WebhookSignatureHeader, in: "header", description: "sha256= and the hex HMAC-SHA256 of the timestamp, a dot and the body"}),
+		},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{"description": "Any 2xx status acknowledges the delivery; other statuses are retried"},
+		},
+	}
+	if t != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				MediaTypeJSON: map[string]interface{}{"schema": db.schemaFor(t)},
+			},
+		}
+	}
+	return op
+}
@@ -0,0 +1,207 @@
+package requiem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type OrderShipped struct {
+	OrderID string `json:"order_id" validate:"required"`
+	Carrier string `json:"carrier"`
+}
+
+type OrderRequest struct {
+	CallbackURL string `json:"callback_url"`
+}
+
+type shipmentsController struct{}
+
+func (shipmentsController) Load(router *Router) {
+	r := router.NewRestRouter("/orders")
+	r.Post("/", func(ctx HTTPContext) {}, OrderRequest{}).
+		Callback("shipped", "{$request.body#/callback_url}", OrderShipped{})
+}
+
+// webhookReceiver records deliveries, answering with the next of statuses
+// (200 once they run out).
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+}
+
+func (rc *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.bodies = append(rc.bodies, string(body))
+	rc.headers = append(rc.headers, r.Header.Clone())
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func webhookTestRouter(t *testing.T, cfg WebhookConfig, hooks ...*Webhook) *Router {
+	t.Helper()
+	db := testDB(t, "")
+	cfg.Admin = func(ctx HTTPContext) bool {
+		if ctx.Request.Header.Get("X-User") != "admin" {
+			ctx.SendStatus(http.StatusForbidden)
+			return false
+		}
+		return true
+	}
+	return newRouter(defaultBasePath, db, []IHttpController{shipmentsController{}, &webhookController{hooks: hooks, cfg: &cfg}})
+}
+
+func TestWebhook_Spec(t *testing.T) {
+	s := NewServer(shipmentsController{})
+	s.UseOpenAPI(OpenAPIConfig{Title: "T", Version: "1", OpenAPIVersion: OpenAPIVersion31})
+	s.Webhook("order.shipped", OrderShipped{}).Summary("Order shipped").Tags("orders")
+
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(s.GetOpenAPISpec(), &spec))
+	op := spec["webhooks"].(map[string]interface{})["order.shipped"].(map[string]interface{})["post"].(map[string]interface{})
+	assert.Equal(t, "Order shipped", op["summary"])
+	assert.Equal(t, []interface{}{"orders"}, op["tags"])
+	body := op["requestBody"].(map[string]interface{})["content"].(map[string]interface{})[MediaTypeJSON].(map[string]interface{})
+	assert.Equal(t, openapiRefPrefix+"OrderShipped", body["schema"].(map[string]interface{})["$ref"])
+	assert.Len(t, op["parameters"], 4)
+	assert.Contains(t, spec["components"].(map[string]interface{})["schemas"], "OrderShipped")
+
+	post := spec["paths"].(map[string]interface{})["/orders/"].(map[string]interface{})["post"].(map[string]interface{})
+	cb := post["callbacks"].(map[string]interface{})["shipped"].(map[string]interface{})
+	assert.Contains(t, cb["{$request.body#/callback_url}"], "post")
+
+	s = NewServer()
+	s.UseOpenAPI(OpenAPIConfig{Title: "T", Version: "1"})
+	s.Webhook("order.shipped", OrderShipped{})
+	spec = nil
+	assert.NoError(t, json.Unmarshal(s.GetOpenAPISpec(), &spec))
+	assert.Contains(t, spec, "x-webhooks")
+	assert.NotContains(t, spec, "webhooks")
+}
+
+func TestWebhook_DeliversSigned(t *testing.T) {
+	rc := &webhookReceiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	hook := &Webhook{name: "order.shipped"}
+	hook.payloadType = reflect.TypeOf(OrderShipped{})
+	r := webhookTestRouter(t, WebhookConfig{Secret: "s3cret"}, hook)
+
+	_, err := hook.Enqueue(r.DB, srv.URL, "not an order")
+	assert.EqualError(t, err, `webhook "order.shipped" expects a requiem.OrderShipped payload, got string`)
+	del, err := hook.Enqueue(r.DB, srv.URL, &OrderShipped{OrderID: "o1", Carrier: "ups"})
+	assert.NoError(t, err)
+
+	sent, err := r.webhookDispatcher.deliverDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	assert.Equal(t, []string{`{"order_id":"o1","carrier":"ups"}`}, rc.bodies)
+	h := rc.headers[0]
+	assert.Equal(t, del.EventID, h.Get(WebhookIDHeader))
+	assert.Equal(t, "order.shipped", h.Get(WebhookEventHeader))
+	assert.NoError(t, VerifyWebhookSignature("s3cret", h, []byte(rc.bodies[0]), time.Minute))
+	assert.ErrorIs(t, VerifyWebhookSignature("other", h, []byte(rc.bodies[0]), time.Minute), ErrWebhookSignature)
+	assert.ErrorIs(t, VerifyWebhookSignature("s3cret", h, []byte(`{}`), time.Minute), ErrWebhookSignature)
+
+	var n int64
+	r.DB.Model(&WebhookDelivery{}).Count(&n)
+	assert.Zero(t, n)
+}
+
+func TestWebhook_RetriesDeadLettersAndReplays(t *testing.T) {
+	rc := &webhookReceiver{statuses: []int{500, 503, 502}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	r := webhookTestRouter(t, WebhookConfig{MaxAttempts: 3, BaseDelay: time.Minute})
+	_, err := EnqueueCallback(r.DB, "shipped", srv.URL, OrderShipped{OrderID: "o1"})
+	assert.NoError(t, err)
+
+	d := r.webhookDispatcher
+	now := time.Now().UTC()
+	d.now = func() time.Time { return now }
+
+	deliver := func() int {
+		sent, err := d.deliverDue(context.Background())
+		assert.NoError(t, err)
+		return sent
+	}
+	assert.Equal(t, 0, deliver())
+	var del WebhookDelivery
+	assert.NoError(t, r.DB.First(&del).Error)
+	assert.Equal(t, 1, del.Attempts)
+	assert.Equal(t, 500, del.LastStatus)
+	assert.True(t, del.NextAttemptAt.Equal(now.Add(time.Minute)))
+
+	// Not due yet, then backing off exponentially.
+	assert.Equal(t, 0, deliver())
+	assert.Len(t, rc.bodies, 1)
+	now = now.Add(time.Minute)
+	deliver()
+	assert.NoError(t, r.DB.First(&del).Error)
+	assert.True(t, del.NextAttemptAt.Equal(now.Add(2*time.Minute)))
+	now = now.Add(2 * time.Minute)
+	deliver()
+	assert.Len(t, rc.bodies, 3)
+
+	var n int64
+	r.DB.Model(&WebhookDelivery{}).Count(&n)
+	assert.Zero(t, n)
+
+	admin := map[string]string{"X-User": "admin"}
+	rec := serve(r, http.MethodGet, "/api/orders/webhooks/dead-letters", "", nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = serve(r, http.MethodGet, "/api/orders/webhooks/dead-letters", "", admin)
+	var letters []WebhookDeadLetter
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &letters))
+	assert.Len(t, letters, 1)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Equal(t, "status 502: ", letters[0].LastError)
+
+	rec = serve(r, http.MethodPost, fmt.Sprintf("/api/orders/webhooks/dead-letters/%d/replay", letters[0].ID), "", admin)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	now = time.Now().UTC()
+	assert.Equal(t, 1, deliver())
+	assert.Len(t, rc.bodies, 4)
+	assert.Equal(t, rc.headers[0].Get(WebhookIDHeader), rc.headers[3].Get(WebhookIDHeader))
+	assert.Empty(t, rc.headers[3].Get(WebhookSignatureHeader))
+
+	rec = serve(r, http.MethodPost, "/api/orders/webhooks/dead-letters/99/replay", "", admin)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestWebhook_Backoff(t *testing.T) {
+	d := newWebhookDispatcher(nil, WebhookConfig{BaseDelay: time.Second, MaxDelay: 10 * time.Second})
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, 4*time.Second, d.backoff(3))
+	assert.Equal(t, 10*time.Second, d.backoff(5))
+	assert.Equal(t, 10*time.Second, d.backoff(50))
+}
+
+func TestWebhook_AdminPathIndependentOfControllerOrder(t *testing.T) {
+	cfg := WebhookConfig{Admin: func(ctx HTTPContext) bool { return true }}
+	r := newRouter(defaultBasePath, testDB(t, ""), []IHttpController{&webhookController{cfg: &cfg}, shipmentsController{}})
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/api/orders/webhooks/dead-letters", "", nil).Code)
+	assert.Empty(t, r.loaded)
+}